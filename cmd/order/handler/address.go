@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"order/infrastructure/constant"
+	"order/infrastructure/log"
+	"order/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *OrderHandler) GetAddresses(c *gin.Context) {
+	userIDstr, isExist := c.Get("user_id")
+	if !isExist {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error_message": "Unauthorized",
+		})
+
+		return
+	}
+
+	userID, ok := userIDstr.(float64)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error_message": "Invalid user id",
+		})
+
+		return
+	}
+
+	addresses, err := h.OrderUsecase.GetAddresses(c.Request.Context(), int64(userID))
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"user_id": int64(userID),
+		}).Errorf("h.OrderUsecase.GetAddresses() got error: %v", err)
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error_message": "Internal server error",
+			"error_detail":  err.Error(),
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error_message": "success.", "data": addresses})
+}
+
+func (h *OrderHandler) CreateAddress(c *gin.Context) {
+	var request models.AddressRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error_message": "Invalid request.",
+			"error_detail":  err.Error(),
+		})
+
+		return
+	}
+
+	userIDstr, isExist := c.Get("user_id")
+	if !isExist {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error_message": "Unauthorized",
+		})
+
+		return
+	}
+
+	userID, ok := userIDstr.(float64)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error_message": "Invalid user id",
+		})
+
+		return
+	}
+
+	address, err := h.OrderUsecase.CreateAddress(c.Request.Context(), int64(userID), request)
+	if err != nil {
+		h.addressError(c, "h.OrderUsecase.CreateAddress()", request, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"error_message": "address created.", "data": address})
+}
+
+func (h *OrderHandler) UpdateAddress(c *gin.Context) {
+	var request models.AddressRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error_message": "Invalid request.",
+			"error_detail":  err.Error(),
+		})
+
+		return
+	}
+
+	param, isValid := addressParam(c)
+	if !isValid {
+		return
+	}
+
+	address, err := h.OrderUsecase.UpdateAddress(c.Request.Context(), &param, request)
+	if err != nil {
+		h.addressError(c, "h.OrderUsecase.UpdateAddress()", request, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error_message": "address updated.", "data": address})
+}
+
+func (h *OrderHandler) DeleteAddress(c *gin.Context) {
+	param, isValid := addressParam(c)
+	if !isValid {
+		return
+	}
+
+	err := h.OrderUsecase.DeleteAddress(c.Request.Context(), &param)
+	if err != nil {
+		h.addressError(c, "h.OrderUsecase.DeleteAddress()", param, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error_message": "address deleted."})
+}
+
+// addressParam reads the user and the address id of the path, it writes the response when either is invalid.
+func addressParam(c *gin.Context) (models.AddressParam, bool) {
+	userIDstr, isExist := c.Get("user_id")
+	if !isExist {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error_message": "Unauthorized",
+		})
+
+		return models.AddressParam{}, false
+	}
+
+	userID, ok := userIDstr.(float64)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error_message": "Invalid user id",
+		})
+
+		return models.AddressParam{}, false
+	}
+
+	addressID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || addressID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error_message": "Invalid address id",
+		})
+
+		return models.AddressParam{}, false
+	}
+
+	return models.AddressParam{
+		UserID:    int64(userID),
+		AddressID: addressID,
+	}, true
+}
+
+func (h *OrderHandler) addressError(c *gin.Context, caller string, param any, err error) {
+	if errors.Is(err, constant.ErrAddressNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error_message": "Address not found",
+		})
+
+		return
+	}
+
+	if errors.Is(err, constant.ErrInvalidAddress) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error_message": "Invalid address",
+			"error_detail":  err.Error(),
+		})
+
+		return
+	}
+
+	if errors.Is(err, constant.ErrAddressLimitReached) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error_message": "Address book is full",
+			"error_detail":  err.Error(),
+		})
+
+		return
+	}
+
+	log.Logger.WithFields(logrus.Fields{
+		"param": param,
+	}).Errorf("%s got error: %v", caller, err)
+
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error_message": "Internal server error",
+		"error_detail":  err.Error(),
+	})
+}
@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"order/infrastructure/cart"
+	"order/infrastructure/constant"
+	"order/infrastructure/log"
+	"order/infrastructure/product"
+	"order/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *OrderHandler) CreateCart(c *gin.Context) {
+	param := cartParam(c)
+
+	response, err := h.OrderUsecase.CreateCart(c.Request.Context(), &param)
+	if err != nil {
+		h.cartError(c, "h.OrderUsecase.CreateCart()", param, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error_message": "success.", "data": response})
+}
+
+func (h *OrderHandler) GetCart(c *gin.Context) {
+	param := cartParam(c)
+
+	response, err := h.OrderUsecase.GetCart(c.Request.Context(), &param)
+	if err != nil {
+		h.cartError(c, "h.OrderUsecase.GetCart()", param, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error_message": "success.", "data": response})
+}
+
+func (h *OrderHandler) AddCartItem(c *gin.Context) {
+	var request models.CartItemRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error_message": "Invalid request.",
+			"error_detail":  err.Error(),
+		})
+
+		return
+	}
+
+	if request.ProductID <= 0 || request.Quantity <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error_message": "Invalid parameter",
+		})
+
+		return
+	}
+
+	param := cartParam(c)
+
+	response, err := h.OrderUsecase.AddCartItem(c.Request.Context(), &param, request)
+	if err != nil {
+		h.cartError(c, "h.OrderUsecase.AddCartItem()", param, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error_message": "success.", "data": response})
+}
+
+func (h *OrderHandler) UpdateCartItem(c *gin.Context) {
+	var request models.CartItemRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error_message": "Invalid request.",
+			"error_detail":  err.Error(),
+		})
+
+		return
+	}
+
+	productID, err := strconv.ParseInt(c.Param("product_id"), 10, 64)
+	if err != nil || productID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error_message": "Invalid product id",
+		})
+
+		return
+	}
+
+	param := cartParam(c)
+	request.ProductID = productID
+
+	response, err := h.OrderUsecase.UpdateCartItem(c.Request.Context(), &param, request)
+	if err != nil {
+		h.cartError(c, "h.OrderUsecase.UpdateCartItem()", param, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error_message": "success.", "data": response})
+}
+
+func (h *OrderHandler) RemoveCartItem(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("product_id"), 10, 64)
+	if err != nil || productID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error_message": "Invalid product id",
+		})
+
+		return
+	}
+
+	param := cartParam(c)
+
+	response, err := h.OrderUsecase.RemoveCartItem(c.Request.Context(), &param, productID)
+	if err != nil {
+		h.cartError(c, "h.OrderUsecase.RemoveCartItem()", param, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error_message": "success.", "data": response})
+}
+
+// MergeCart is called once a guest signs in, to carry the guest cart over to the user's cart.
+func (h *OrderHandler) MergeCart(c *gin.Context) {
+	var request models.MergeCartRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error_message": "Invalid request.",
+			"error_detail":  err.Error(),
+		})
+
+		return
+	}
+
+	param := cartParam(c)
+	if param.UserID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error_message": "Unauthorized",
+		})
+
+		return
+	}
+
+	if strings.TrimSpace(request.GuestCartID) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error_message": "Invalid parameter",
+		})
+
+		return
+	}
+
+	response, err := h.OrderUsecase.MergeCart(c.Request.Context(), &param, strings.TrimSpace(request.GuestCartID))
+	if err != nil {
+		h.cartError(c, "h.OrderUsecase.MergeCart()", param, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error_message": "success.", "data": response})
+}
+
+// cartParam reads the cart of the path and the signed-in user, zero for a guest.
+func cartParam(c *gin.Context) models.CartParam {
+	param := models.CartParam{
+		CartID:   c.Param("cart_id"),
+		Currency: c.Query("currency"),
+	}
+
+	if userIDstr, isExist := c.Get("user_id"); isExist {
+		if userID, ok := userIDstr.(float64); ok {
+			param.UserID = int64(userID)
+		}
+	}
+
+	return param
+}
+
+func (h *OrderHandler) cartError(c *gin.Context, caller string, param models.CartParam, err error) {
+	if errors.Is(err, cart.ErrCartNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error_message": "Cart not found",
+		})
+
+		return
+	}
+
+	if errors.Is(err, cart.ErrCartConflict) || errors.Is(err, cart.ErrCartExists) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error_message": "Cart was changed by another request, please try again",
+		})
+
+		return
+	}
+
+	if errors.Is(err, product.ErrProductNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error_message": "Product not found",
+			"error_detail":  err.Error(),
+		})
+
+		return
+	}
+
+	if errors.Is(err, constant.ErrInvalidCart) || errors.Is(err, constant.ErrInvalidCheckout) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error_message": "Invalid cart",
+			"error_detail":  err.Error(),
+		})
+
+		return
+	}
+
+	if errors.Is(err, product.ErrProductServiceUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error_message": "Product service unavailable, please try again later",
+			"error_detail":  err.Error(),
+		})
+
+		return
+	}
+
+	log.Logger.WithFields(logrus.Fields{
+		"param": param,
+	}).Errorf("%s got error: %v", caller, err)
+
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error_message": "Internal server error",
+		"error_detail":  err.Error(),
+	})
+}
@@ -1,11 +1,17 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"order/cmd/order/usecase"
+	"order/infrastructure/constant"
 	"order/infrastructure/log"
+	"order/infrastructure/product"
 	"order/models"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -58,7 +64,7 @@ func (h *OrderHandler) CheckoutOrder(c *gin.Context) {
 		return
 	}
 
-	if len(param.Items) == 0 {
+	if len(param.Items) == 0 && param.CartID == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error_message": "Invalid parameter",
 		})
@@ -66,9 +72,50 @@ func (h *OrderHandler) CheckoutOrder(c *gin.Context) {
 		return
 	}
 
+	// the token may also come as the standard header
+	if param.IdempontencyToken == "" {
+		param.IdempontencyToken = c.GetHeader("Idempotency-Key")
+	}
+
 	param.UserID = int64(userID)
-	orderID, err := h.OrderUsecase.CheckoutOrder(c.Request.Context(), &param)
+	response, err := h.OrderUsecase.CheckoutOrder(c.Request.Context(), &param)
 	if err != nil {
+		if errors.Is(err, constant.ErrIdempotencyInFlight) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error_message": "Order is still being processed",
+				"error_detail":  err.Error(),
+			})
+
+			return
+		}
+
+		if errors.Is(err, product.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error_message": "Product not found",
+				"error_detail":  err.Error(),
+			})
+
+			return
+		}
+
+		if errors.Is(err, constant.ErrInvalidCheckout) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error_message": "Invalid checkout",
+				"error_detail":  err.Error(),
+			})
+
+			return
+		}
+
+		if errors.Is(err, product.ErrProductServiceUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error_message": "Product service unavailable, please try again later",
+				"error_detail":  err.Error(),
+			})
+
+			return
+		}
+
 		log.Logger.WithFields(logrus.Fields{
 			"param": param,
 		}).Errorf("h.OrderUsecase.CheckoutOrder() got error %v", err)
@@ -81,14 +128,80 @@ func (h *OrderHandler) CheckoutOrder(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"error_message": "order created.", "order_id": orderID})
+	if response.Replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error_message": "order created.", "order_id": response.OrderID})
 
 	return
 }
 
-func (h *OrderHandler) GetOrderHistory(c *gin.Context) {
-	var param models.OrderHistoryParam
+func (h *OrderHandler) QuoteShipping(c *gin.Context) {
+	var param models.ShippingQuoteRequest
+
+	if err := c.ShouldBindJSON(&param); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error_message": "Invalid request.",
+			"error_detail":  err.Error(),
+		})
+
+		return
+	}
+
+	if len(param.Items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error_message": "Invalid parameter",
+		})
+
+		return
+	}
+
+	quote, err := h.OrderUsecase.QuoteShipping(c.Request.Context(), &param)
+	if err != nil {
+		if errors.Is(err, product.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error_message": "Product not found",
+				"error_detail":  err.Error(),
+			})
+
+			return
+		}
+
+		if errors.Is(err, constant.ErrInvalidCheckout) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error_message": "Invalid shipping quote",
+				"error_detail":  err.Error(),
+			})
+
+			return
+		}
+
+		if errors.Is(err, product.ErrProductServiceUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error_message": "Product service unavailable, please try again later",
+				"error_detail":  err.Error(),
+			})
+
+			return
+		}
+
+		log.Logger.WithFields(logrus.Fields{
+			"param": param,
+		}).Errorf("h.OrderUsecase.QuoteShipping() got error %v", err)
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error_message": "Internal server error",
+			"error_detail":  err.Error(),
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error_message": "success.", "data": quote})
+}
 
+func (h *OrderHandler) GetOrderHistory(c *gin.Context) {
 	userIDstr, isExist := c.Get("user_id")
 	if !isExist {
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -105,16 +218,29 @@ func (h *OrderHandler) GetOrderHistory(c *gin.Context) {
 		return
 	}
 
-	statusStr := c.DefaultQuery("status", "0")
-	status, _ := strconv.Atoi(statusStr)
+	param, err := parseOrderHistoryQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error_message": "Invalid parameter",
+			"error_detail":  err.Error(),
+		})
 
-	param = models.OrderHistoryParam{
-		UserID: int64(userID),
-		Status: status,
+		return
 	}
 
+	param.UserID = int64(userID)
+
 	orderHistory, err := h.OrderUsecase.GetOrderHistoryByUserID(c.Request.Context(), &param)
 	if err != nil {
+		if errors.Is(err, constant.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error_message": "Invalid parameter",
+				"error_detail":  err.Error(),
+			})
+
+			return
+		}
+
 		log.Logger.WithFields(logrus.Fields{
 			"param": param,
 		}).Errorf("h.OrderUsecase.GetOrderHistoryByUserID() got error: %v", err)
@@ -127,7 +253,225 @@ func (h *OrderHandler) GetOrderHistory(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"error_message": "success.", "data": orderHistory})
+	c.JSON(http.StatusOK, gin.H{
+		"error_message": "success.",
+		"data":          orderHistory.Orders,
+		"next_cursor":   orderHistory.NextCursor,
+		"has_more":      orderHistory.HasMore,
+	})
 
 	return
 }
+
+// parseOrderHistoryQuery reads the history filters:
+// ?status=0,1&created_from=2025-01-01&created_to=2025-01-31&sort=desc&limit=20&cursor=xxx
+func parseOrderHistoryQuery(c *gin.Context) (models.OrderHistoryParam, error) {
+	param := models.OrderHistoryParam{
+		Cursor:        c.Query("cursor"),
+		Limit:         constant.OrderHistoryDefaultLimit,
+		SortDirection: strings.ToLower(c.DefaultQuery("sort", constant.SortDirectionDesc)),
+	}
+
+	if param.SortDirection != constant.SortDirectionAsc && param.SortDirection != constant.SortDirectionDesc {
+		return models.OrderHistoryParam{}, fmt.Errorf("sort must be %s or %s", constant.SortDirectionAsc, constant.SortDirectionDesc)
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > constant.OrderHistoryMaxLimit {
+			return models.OrderHistoryParam{}, fmt.Errorf("limit must be between 1 and %d", constant.OrderHistoryMaxLimit)
+		}
+
+		param.Limit = limit
+	}
+
+	if statusStr := c.Query("status"); statusStr != "" {
+		for _, value := range strings.Split(statusStr, ",") {
+			status, err := strconv.Atoi(strings.TrimSpace(value))
+			if _, isExist := constant.OrderStatusTranslated[status]; err != nil || !isExist {
+				return models.OrderHistoryParam{}, fmt.Errorf("invalid status %q", value)
+			}
+
+			param.Statuses = append(param.Statuses, status)
+		}
+	}
+
+	if fromStr := c.Query("created_from"); fromStr != "" {
+		from, _, err := parseQueryTime(fromStr)
+		if err != nil {
+			return models.OrderHistoryParam{}, fmt.Errorf("invalid created_from: %v", err)
+		}
+
+		param.StartTime = &from
+	}
+
+	if toStr := c.Query("created_to"); toStr != "" {
+		to, isDate, err := parseQueryTime(toStr)
+		if err != nil {
+			return models.OrderHistoryParam{}, fmt.Errorf("invalid created_to: %v", err)
+		}
+
+		// a plain date includes the whole day
+		if isDate {
+			to = to.Add(24*time.Hour - time.Microsecond)
+		}
+
+		param.EndTime = &to
+	}
+
+	return param, nil
+}
+
+// parseQueryTime accepts either RFC3339 or YYYY-MM-DD and reports whether a plain date was given.
+func parseQueryTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	return t, false, nil
+}
+
+func (h *OrderHandler) GetOrderByID(c *gin.Context) {
+	userIDstr, isExist := c.Get("user_id")
+	if !isExist {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error_message": "Unauthorized",
+		})
+
+		return
+	}
+
+	userID, ok := userIDstr.(float64)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error_message": "Invalid user id",
+		})
+
+		return
+	}
+
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || orderID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error_message": "Invalid order id",
+		})
+
+		return
+	}
+
+	param := models.OrderInfoParam{
+		UserID:  int64(userID),
+		OrderID: orderID,
+	}
+
+	order, err := h.OrderUsecase.GetOrderByID(c.Request.Context(), &param)
+	if err != nil {
+		if errors.Is(err, constant.ErrOrderNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error_message": "Order not found",
+			})
+
+			return
+		}
+
+		log.Logger.WithFields(logrus.Fields{
+			"param": param,
+		}).Errorf("h.OrderUsecase.GetOrderByID() got error: %v", err)
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error_message": "Internal server error",
+			"error_detail":  err.Error(),
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error_message": "success.", "data": order})
+}
+
+func (h *OrderHandler) CancelOrder(c *gin.Context) {
+	var request models.CancelOrderRequest
+
+	// the body is optional, an empty one cancels without a reason
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error_message": "Invalid request.",
+				"error_detail":  err.Error(),
+			})
+
+			return
+		}
+	}
+
+	userIDstr, isExist := c.Get("user_id")
+	if !isExist {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error_message": "Unauthorized",
+		})
+
+		return
+	}
+
+	userID, ok := userIDstr.(float64)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error_message": "Invalid user id",
+		})
+
+		return
+	}
+
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || orderID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error_message": "Invalid order id",
+		})
+
+		return
+	}
+
+	param := models.CancelOrderParam{
+		UserID:  int64(userID),
+		OrderID: orderID,
+		Reason:  strings.TrimSpace(request.Reason),
+	}
+
+	err = h.OrderUsecase.CancelOrder(c.Request.Context(), &param)
+	if err != nil {
+		if errors.Is(err, constant.ErrOrderNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error_message": "Order not found",
+			})
+
+			return
+		}
+
+		if errors.Is(err, constant.ErrInvalidStatusTransition) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error_message": "Order can no longer be cancelled",
+				"error_detail":  err.Error(),
+			})
+
+			return
+		}
+
+		log.Logger.WithFields(logrus.Fields{
+			"param": param,
+		}).Errorf("h.OrderUsecase.CancelOrder() got error: %v", err)
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error_message": "Internal server error",
+			"error_detail":  err.Error(),
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error_message": "order cancelled.", "order_id": orderID})
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"order/infrastructure/constant"
+	"order/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetAddressesByUserID returns the address book of the user, the default address first.
+func (r *OrderRepository) GetAddressesByUserID(ctx context.Context, userID int64) ([]models.Address, error) {
+	var addresses []models.Address
+	err := r.Database.WithContext(ctx).Table("addresses").
+		Where("user_id = ?", userID).
+		Order("is_default DESC, id ASC").
+		Find(&addresses).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return addresses, nil
+}
+
+func (r *OrderRepository) GetAddressByID(ctx context.Context, addressID, userID int64) (models.Address, error) {
+	var address models.Address
+	err := r.Database.WithContext(ctx).Table("addresses").
+		Where("id = ? AND user_id = ?", addressID, userID).
+		Take(&address).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Address{}, constant.ErrAddressNotFound
+		}
+
+		return models.Address{}, err
+	}
+
+	return address, nil
+}
+
+func (r *OrderRepository) GetDefaultAddress(ctx context.Context, userID int64) (models.Address, error) {
+	var address models.Address
+	err := r.Database.WithContext(ctx).Table("addresses").
+		Where("user_id = ? AND is_default", userID).
+		Take(&address).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Address{}, constant.ErrAddressNotFound
+		}
+
+		return models.Address{}, err
+	}
+
+	return address, nil
+}
+
+// LockAddressesByUserIDTx locks the address book of the user, so concurrent changes cannot overrun the
+// address limit or leave two default addresses.
+func (r *OrderRepository) LockAddressesByUserIDTx(ctx context.Context, tx *gorm.DB, userID int64) ([]models.Address, error) {
+	var addresses []models.Address
+	err := tx.WithContext(ctx).Table("addresses").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).
+		Order("id ASC").
+		Find(&addresses).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return addresses, nil
+}
+
+func (r *OrderRepository) InsertAddressTx(ctx context.Context, tx *gorm.DB, address *models.Address) error {
+	return tx.WithContext(ctx).Table("addresses").Create(address).Error
+}
+
+func (r *OrderRepository) UpdateAddressTx(ctx context.Context, tx *gorm.DB, address *models.Address) error {
+	result := tx.WithContext(ctx).Table("addresses").
+		Where("id = ? AND user_id = ?", address.ID, address.UserID).
+		Updates(map[string]any{
+			"label":       address.Label,
+			"recipient":   address.Recipient,
+			"phone":       address.Phone,
+			"line1":       address.Line1,
+			"line2":       address.Line2,
+			"city":        address.City,
+			"region":      address.Region,
+			"postal_code": address.PostalCode,
+			"country":     address.Country,
+			"is_default":  address.IsDefault,
+			"update_time": address.UpdateTime,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return constant.ErrAddressNotFound
+	}
+
+	return nil
+}
+
+// ClearDefaultAddressTx unsets the default address of the user, except addressID.
+func (r *OrderRepository) ClearDefaultAddressTx(ctx context.Context, tx *gorm.DB, userID, addressID int64) error {
+	return tx.WithContext(ctx).Table("addresses").
+		Where("user_id = ? AND id <> ? AND is_default", userID, addressID).
+		Update("is_default", false).Error
+}
+
+func (r *OrderRepository) DeleteAddressTx(ctx context.Context, tx *gorm.DB, addressID, userID int64) error {
+	result := tx.WithContext(ctx).Table("addresses").
+		Where("id = ? AND user_id = ?", addressID, userID).
+		Delete(&models.Address{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return constant.ErrAddressNotFound
+	}
+
+	return nil
+}
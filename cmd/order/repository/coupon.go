@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"order/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *OrderRepository) GetCouponsByCodes(ctx context.Context, codes []string) ([]models.Coupon, error) {
+	var coupons []models.Coupon
+	err := r.Database.WithContext(ctx).Table("coupons").Where("code IN ?", codes).Find(&coupons).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return coupons, nil
+}
+
+// LockCouponsTx locks the coupons in id order, so concurrent checkouts sharing coupons cannot deadlock.
+func (r *OrderRepository) LockCouponsTx(ctx context.Context, tx *gorm.DB, couponIDs []int64) ([]models.Coupon, error) {
+	var coupons []models.Coupon
+	err := tx.WithContext(ctx).Table("coupons").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", couponIDs).
+		Order("id ASC").
+		Find(&coupons).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return coupons, nil
+}
+
+// CountCouponRedemptionsByUser returns how many times the user redeemed each coupon, leaving out the
+// redemptions of orders that gave their coupons back.
+func (r *OrderRepository) CountCouponRedemptionsByUser(ctx context.Context, couponIDs []int64, userID int64) (map[int64]int, error) {
+	return r.CountCouponRedemptionsByUserTx(ctx, r.Database, couponIDs, userID)
+}
+
+func (r *OrderRepository) CountCouponRedemptionsByUserTx(ctx context.Context, tx *gorm.DB, couponIDs []int64, userID int64) (map[int64]int, error) {
+	var rows []struct {
+		CouponID int64
+		Total    int
+	}
+
+	err := tx.WithContext(ctx).Table("coupon_redemptions").
+		Select("coupon_id, COUNT(*) AS total").
+		Where("coupon_id IN ? AND user_id = ? AND released_time IS NULL", couponIDs, userID).
+		Group("coupon_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[int64]int, len(rows))
+	for _, row := range rows {
+		result[row.CouponID] = row.Total
+	}
+
+	return result, nil
+}
+
+func (r *OrderRepository) InsertCouponRedemptionsTx(ctx context.Context, tx *gorm.DB, redemptions []models.CouponRedemption) error {
+	if len(redemptions) == 0 {
+		return nil
+	}
+
+	err := tx.WithContext(ctx).Table("coupon_redemptions").Create(&redemptions).Error
+	if err != nil {
+		return err
+	}
+
+	for _, redemption := range redemptions {
+		err = tx.WithContext(ctx).Table("coupons").
+			Where("id = ?", redemption.CouponID).
+			Update("used_count", gorm.Expr("used_count + 1")).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReleaseCouponRedemptionsTx gives the coupons of an order back, for orders that will never be paid. The
+// redemptions are kept, marked released, so the order still shows the discount it was priced with.
+func (r *OrderRepository) ReleaseCouponRedemptionsTx(ctx context.Context, tx *gorm.DB, orderID int64) error {
+	var redemptions []models.CouponRedemption
+	err := tx.WithContext(ctx).Table("coupon_redemptions").
+		Model(&redemptions).
+		Clauses(clause.Returning{}).
+		Where("order_id = ? AND released_time IS NULL", orderID).
+		Update("released_time", time.Now()).Error
+	if err != nil {
+		return err
+	}
+
+	for _, redemption := range redemptions {
+		err = tx.WithContext(ctx).Table("coupons").
+			Where("id = ?", redemption.CouponID).
+			Update("used_count", gorm.Expr("GREATEST(used_count - 1, 0)")).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetCouponRedemptionsByOrderIDs returns the coupons applied to every order, keyed by order id.
+func (r *OrderRepository) GetCouponRedemptionsByOrderIDs(ctx context.Context, orderIDs []int64) (map[int64][]models.CouponRedemption, error) {
+	result := make(map[int64][]models.CouponRedemption, len(orderIDs))
+	if len(orderIDs) == 0 {
+		return result, nil
+	}
+
+	var redemptions []models.CouponRedemption
+	err := r.Database.WithContext(ctx).Table("coupon_redemptions").
+		Where("order_id IN ?", orderIDs).
+		Order("order_id ASC, id ASC").
+		Find(&redemptions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, redemption := range redemptions {
+		result[redemption.OrderID] = append(result[redemption.OrderID], redemption)
+	}
+
+	return result, nil
+}
+
+// GetOrderDiscounts returns the coupons applied to an order.
+func (r *OrderRepository) GetOrderDiscounts(ctx context.Context, orderID int64) ([]models.AppliedDiscount, error) {
+	redemptions, err := r.GetCouponRedemptionsByOrderIDs(ctx, []int64{orderID})
+	if err != nil {
+		return nil, err
+	}
+
+	return appliedDiscounts(redemptions[orderID]), nil
+}
+
+func appliedDiscounts(redemptions []models.CouponRedemption) []models.AppliedDiscount {
+	result := make([]models.AppliedDiscount, len(redemptions))
+	for index, redemption := range redemptions {
+		result[index] = models.AppliedDiscount{
+			Code:   redemption.Code,
+			Amount: redemption.DiscountAmount,
+		}
+	}
+
+	return result
+}
@@ -2,13 +2,14 @@ package repository
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
 	"order/infrastructure/constant"
 	"order/models"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func (r *OrderRepository) WithTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
@@ -29,6 +30,12 @@ func (r *OrderRepository) WithTransaction(ctx context.Context, fn func(tx *gorm.
 	return tx.Commit().Error
 }
 
+// WithSavepointTx runs fn in a savepoint of tx, an error from fn rolls back only what fn did and
+// leaves tx usable.
+func (r *OrderRepository) WithSavepointTx(ctx context.Context, tx *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return tx.WithContext(ctx).Transaction(fn)
+}
+
 /*
 	withTransaction(
 		insert order detail
@@ -40,8 +47,12 @@ func (r *OrderRepository) WithTransaction(ctx context.Context, fn func(tx *gorm.
 
 func (r *OrderRepository) GetOrderInfoByOrderID(ctx context.Context, orderID int64) (models.Order, error) {
 	var result models.Order
-	err := r.Database.Table("orders").WithContext(ctx).Where("order_id = ?", orderID).Find(&result).Error
+	err := r.Database.Table("orders").WithContext(ctx).Where("id = ?", orderID).Take(&result).Error
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Order{}, constant.ErrOrderNotFound
+		}
+
 		return models.Order{}, err
 	}
 
@@ -50,14 +61,49 @@ func (r *OrderRepository) GetOrderInfoByOrderID(ctx context.Context, orderID int
 
 func (r *OrderRepository) GetOrderDetailByID(ctx context.Context, orderDetailID int64) (models.OrderDetail, error) {
 	var result models.OrderDetail
-	err := r.Database.Table("order_detail").WithContext(ctx).Where("id = ?", orderDetailID).Find(&result).Error
+	err := r.Database.Table("order_detail").WithContext(ctx).Where("id = ?", orderDetailID).Take(&result).Error
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.OrderDetail{}, constant.ErrOrderNotFound
+		}
+
 		return models.OrderDetail{}, err
 	}
 
 	return result, nil
 }
 
+func (r *OrderRepository) GetOrderDetailByIDTx(ctx context.Context, tx *gorm.DB, orderDetailID int64) (models.OrderDetail, error) {
+	var result models.OrderDetail
+	err := tx.WithContext(ctx).Table("order_detail").Where("id = ?", orderDetailID).Take(&result).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.OrderDetail{}, constant.ErrOrderNotFound
+		}
+
+		return models.OrderDetail{}, err
+	}
+
+	return result, nil
+}
+
+// LockExpiredOrderIDsTx locks unpaid orders created before param.CreatedBefore. Rows already locked by
+// another replica are skipped, so concurrent schedulers never expire the same order twice.
+func (r *OrderRepository) LockExpiredOrderIDsTx(ctx context.Context, tx *gorm.DB, param *models.ExpireOrdersParam) ([]int64, error) {
+	var orderIDs []int64
+	err := tx.WithContext(ctx).Table("orders").
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status IN ? AND create_time < ?", constant.OrderStatusSources(constant.OrderStatusExpired), param.CreatedBefore).
+		Order("id ASC").
+		Limit(param.Limit).
+		Pluck("id", &orderIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return orderIDs, nil
+}
+
 func (r *OrderRepository) InsertOrderTx(ctx context.Context, tx *gorm.DB, order *models.Order) error {
 	err := tx.WithContext(ctx).Table("orders").Create(order).Error
 
@@ -70,89 +116,154 @@ func (r *OrderRepository) InsertOrderDetailTx(ctx context.Context, tx *gorm.DB,
 	return err
 }
 
-func (r *OrderRepository) CheckIdempotency(ctx context.Context, token string) (bool, error) {
-	var log models.OrderRequestLog
-
-	err := r.Database.WithContext(ctx).Table("order_request_log").First(&log, "idempontency_token = ?", token).Error
+// UpdateOrderStatusTx moves an order to param.Status only when its current status allows it,
+// then records the transition in order_status_history. It returns the order as updated.
+func (r *OrderRepository) UpdateOrderStatusTx(ctx context.Context, tx *gorm.DB, param *models.UpdateOrderStatusParam) (models.Order, error) {
+	// lock the order so the status recorded as from_status is the one being replaced
+	var order models.Order
+	err := tx.WithContext(ctx).Table("orders").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", param.OrderID).
+		Take(&order).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return false, nil
+			return models.Order{}, constant.ErrOrderNotFound
 		}
 
-		return false, err
+		return models.Order{}, err
 	}
 
-	return true, nil
-}
-
-func (r *OrderRepository) SaveIdempontency(ctx context.Context, token string) error {
-	log := models.OrderRequestLog{
-		IdempontencyToken: token,
-		CreateTime:        time.Now(),
+	if !constant.CanTransitionOrderStatus(order.Status, param.Status) {
+		return models.Order{}, &constant.InvalidStatusTransitionError{
+			OrderID: param.OrderID,
+			From:    order.Status,
+			To:      param.Status,
+		}
 	}
 
-	err := r.Database.WithContext(ctx).Table("order_request_log").Create(&log).Error
+	now := time.Now()
+	err = tx.WithContext(ctx).Table("orders").
+		Where("id = ?", param.OrderID).
+		Updates(map[string]interface{}{
+			"status":      param.Status,
+			"update_time": now,
+		}).Error
 	if err != nil {
-		return err
+		return models.Order{}, err
 	}
 
-	return nil
-}
-
-func (r *OrderRepository) UpdateOrderStatus(ctx context.Context, orderID int64, status int) error {
-	err := r.Database.Table("orders").WithContext(ctx).Model(&models.Order{}).Updates(map[string]interface{}{
-		"status":      status,
-		"update_time": time.Now(),
-	}).Where("order_id = ?", orderID).Error
-
+	fromStatus := order.Status
+	err = r.InsertOrderStatusHistoryTx(ctx, tx, &models.OrderStatusHistory{
+		OrderID:       param.OrderID,
+		FromStatus:    &fromStatus,
+		ToStatus:      param.Status,
+		ActorType:     param.ActorType,
+		ActorID:       param.ActorID,
+		Reason:        param.Reason,
+		SourceEventID: param.SourceEventID,
+		CreateTime:    now,
+	})
 	if err != nil {
-		return err
+		return models.Order{}, err
 	}
 
-	return nil
+	order.Status = param.Status
+	order.UpdateTime = now
+
+	return order, nil
 }
 
+// GetOrderHistoryByUserID returns up to param.Limit+1 orders after param.After in keyset order of
+// (create_time, id), so callers can tell whether another page exists.
 func (r *OrderRepository) GetOrderHistoryByUserID(ctx context.Context, param *models.OrderHistoryParam) ([]models.OrderHistoryResponse, error) {
 	var results []models.OrderHistoryResponse
 	var queryResults []models.OrderHistoryResult
 
 	query := r.Database.WithContext(ctx).Table("orders AS o").
-		Select("o.id, o.amount, o.total_qty, o.status, o.payment_method, o.shipping_address, od.products, od.order_history").
+		Select("o.id, o.subtotal, o.discount_amount, o.tax_amount, o.tax_mode, o.shipping_method, o.shipping_fee, o.amount, o.currency, o.total_qty, o.status, o.payment_method, o.shipping_address, o.shipping_address_detail, o.create_time, od.products, od.order_history").
 		Joins("JOIN order_detail od ON od.id = o.order_detail_id").
 		Where("o.user_id = ?", param.UserID)
 
-	if param.Status > 0 {
-		query = query.Where("o.Status = ?", param.Status)
+	if len(param.Statuses) > 0 {
+		query = query.Where("o.status IN ?", param.Statuses)
+	}
+
+	if param.StartTime != nil {
+		query = query.Where("o.create_time >= ?", *param.StartTime)
+	}
+
+	if param.EndTime != nil {
+		query = query.Where("o.create_time <= ?", *param.EndTime)
+	}
+
+	direction := "DESC"
+	comparator := "<"
+	if param.SortDirection == constant.SortDirectionAsc {
+		direction = "ASC"
+		comparator = ">"
+	}
+
+	if param.After != nil {
+		query = query.Where(fmt.Sprintf("(o.create_time, o.id) %s (?, ?)", comparator), param.After.CreateTime, param.After.ID)
 	}
 
-	err := query.Order("o.id DESC").Scan(&queryResults).Error
+	err := query.Order(fmt.Sprintf("o.create_time %s, o.id %s", direction, direction)).
+		Limit(param.Limit + 1).
+		Scan(&queryResults).Error
 	if err != nil {
 		return nil, err
 	}
 
-	for _, result := range queryResults {
-		var products []models.CheckoutItem
-		var orderHistory []models.StatusHistory
+	orderIDs := make([]int64, len(queryResults))
+	for index, result := range queryResults {
+		orderIDs[index] = result.ID
+	}
+
+	orderItems, err := r.GetOrderItemsByOrderIDs(ctx, orderIDs)
+	if err != nil {
+		return nil, err
+	}
 
-		err = json.Unmarshal([]byte(result.Products), &products)
+	statusHistories, err := r.GetOrderStatusHistoryByOrderIDs(ctx, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	redemptions, err := r.GetCouponRedemptionsByOrderIDs(ctx, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range queryResults {
+		products, err := checkoutItemsFromOrder(orderItems[result.ID], result.Products)
 		if err != nil {
 			return nil, err
 		}
 
-		err = json.Unmarshal([]byte(result.OrderHistory), &orderHistory)
+		orderHistory, err := statusHistoryFromOrder(statusHistories[result.ID], result.OrderHistory)
 		if err != nil {
 			return nil, err
 		}
 
 		results = append(results, models.OrderHistoryResponse{
-			OrderID:         result.ID,
-			TotalAmount:     result.Amount,
-			TotalQty:        result.TotalQty,
-			Status:          constant.OrderStatusTranslated[result.Status],
-			PaymentMethod:   result.PaymentMethod,
-			ShippingAddress: result.ShippingAddress,
-			Products:        products,
-			History:         orderHistory,
+			OrderID:               result.ID,
+			Subtotal:              result.Subtotal,
+			DiscountAmount:        result.DiscountAmount,
+			Discounts:             appliedDiscounts(redemptions[result.ID]),
+			TaxAmount:             result.TaxAmount,
+			TaxMode:               result.TaxMode,
+			ShippingMethod:        result.ShippingMethod,
+			ShippingFee:           result.ShippingFee,
+			TotalAmount:           result.Amount,
+			Currency:              result.Currency,
+			TotalQty:              result.TotalQty,
+			Status:                constant.OrderStatusTranslated[result.Status],
+			PaymentMethod:         result.PaymentMethod,
+			ShippingAddress:       result.ShippingAddress,
+			ShippingAddressDetail: result.ShippingAddressDetail,
+			Products:              products,
+			History:               orderHistory,
+			CreateTime:            result.CreateTime,
 		})
 	}
 
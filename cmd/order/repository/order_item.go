@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"order/models"
+
+	"gorm.io/gorm"
+)
+
+func (r *OrderRepository) InsertOrderItemsTx(ctx context.Context, tx *gorm.DB, items []models.OrderItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	return tx.WithContext(ctx).Table("order_items").Create(&items).Error
+}
+
+// GetOrderItemsByOrderIDs returns the items of every order, keyed by order id. Orders placed before
+// order_items existed and not backfilled yet are missing from the result.
+func (r *OrderRepository) GetOrderItemsByOrderIDs(ctx context.Context, orderIDs []int64) (map[int64][]models.OrderItem, error) {
+	result := make(map[int64][]models.OrderItem, len(orderIDs))
+	if len(orderIDs) == 0 {
+		return result, nil
+	}
+
+	var items []models.OrderItem
+	err := r.Database.WithContext(ctx).Table("order_items").
+		Where("order_id IN ?", orderIDs).
+		Order("order_id ASC, id ASC").
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		result[item.OrderID] = append(result[item.OrderID], item)
+	}
+
+	return result, nil
+}
+
+// GetOrderProducts returns the products of an order from order_items.
+func (r *OrderRepository) GetOrderProducts(ctx context.Context, orderID int64, orderDetail models.OrderDetail) ([]models.CheckoutItem, error) {
+	orderItems, err := r.GetOrderItemsByOrderIDs(ctx, []int64{orderID})
+	if err != nil {
+		return nil, err
+	}
+
+	return checkoutItemsFromOrder(orderItems[orderID], orderDetail.Products)
+}
+
+// checkoutItemsFromOrder falls back to the products JSON of order_detail for orders that were not
+// backfilled into order_items yet.
+func checkoutItemsFromOrder(items []models.OrderItem, products string) ([]models.CheckoutItem, error) {
+	if len(items) == 0 {
+		var result []models.CheckoutItem
+		err := json.Unmarshal([]byte(products), &result)
+		if err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	}
+
+	result := make([]models.CheckoutItem, len(items))
+	for index, item := range items {
+		result[index] = models.CheckoutItem{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			Price:     item.UnitPrice,
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"order/infrastructure/constant"
+	"order/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func (r *OrderRepository) InsertOrderStatusHistoryTx(ctx context.Context, tx *gorm.DB, history *models.OrderStatusHistory) error {
+	return tx.WithContext(ctx).Table("order_status_history").Create(history).Error
+}
+
+// GetOrderStatusHistoryByOrderIDs returns the transitions of every order oldest first, keyed by order id.
+func (r *OrderRepository) GetOrderStatusHistoryByOrderIDs(ctx context.Context, orderIDs []int64) (map[int64][]models.OrderStatusHistory, error) {
+	result := make(map[int64][]models.OrderStatusHistory, len(orderIDs))
+	if len(orderIDs) == 0 {
+		return result, nil
+	}
+
+	var histories []models.OrderStatusHistory
+	err := r.Database.WithContext(ctx).Table("order_status_history").
+		Where("order_id IN ?", orderIDs).
+		Order("order_id ASC, id ASC").
+		Find(&histories).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, history := range histories {
+		result[history.OrderID] = append(result[history.OrderID], history)
+	}
+
+	return result, nil
+}
+
+// GetOrderStatusHistory returns the status timeline of an order from order_status_history.
+func (r *OrderRepository) GetOrderStatusHistory(ctx context.Context, orderID int64, orderDetail models.OrderDetail) ([]models.StatusHistory, error) {
+	histories, err := r.GetOrderStatusHistoryByOrderIDs(ctx, []int64{orderID})
+	if err != nil {
+		return nil, err
+	}
+
+	return statusHistoryFromOrder(histories[orderID], orderDetail.OrderHistory)
+}
+
+// statusHistoryFromOrder falls back to the order_history JSON of order_detail for orders placed
+// before order_status_history existed.
+func statusHistoryFromOrder(histories []models.OrderStatusHistory, orderHistory string) ([]models.StatusHistory, error) {
+	if len(histories) == 0 {
+		var result []models.StatusHistory
+		err := json.Unmarshal([]byte(orderHistory), &result)
+		if err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	}
+
+	result := make([]models.StatusHistory, len(histories))
+	for index, history := range histories {
+		result[index] = models.StatusHistory{
+			Status:        statusHistoryName(history.ToStatus),
+			Timestamp:     history.CreateTime.Format(time.RFC3339Nano),
+			Reason:        history.Reason,
+			ActorType:     history.ActorType,
+			ActorID:       history.ActorID,
+			SourceEventID: history.SourceEventID,
+		}
+
+		if history.FromStatus != nil {
+			result[index].FromStatus = statusHistoryName(*history.FromStatus)
+		}
+	}
+
+	return result, nil
+}
+
+func statusHistoryName(status int) string {
+	return strings.ToLower(constant.OrderStatusName(status))
+}
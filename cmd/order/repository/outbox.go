@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"order/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// outboxLockKey is the Postgres advisory lock id held by the replica currently draining the outbox.
+const outboxLockKey = 720190001
+
+func (r *OrderRepository) InsertOutboxMessagesTx(ctx context.Context, tx *gorm.DB, messages []models.OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return tx.WithContext(ctx).Table("order_outbox").Create(&messages).Error
+}
+
+func (r *OrderRepository) TryLockOutboxTx(ctx context.Context, tx *gorm.DB) (bool, error) {
+	var locked bool
+	err := tx.WithContext(ctx).Raw("SELECT pg_try_advisory_xact_lock(?)", outboxLockKey).Scan(&locked).Error
+	if err != nil {
+		return false, err
+	}
+
+	return locked, nil
+}
+
+// GetPendingOutboxMessagesTx returns the unpublished messages due at now. A message is left out while
+// an earlier message of its key is still backing off, claimed or parked, so a key is never published
+// out of order and a stuck key does not fill the batch.
+func (r *OrderRepository) GetPendingOutboxMessagesTx(ctx context.Context, tx *gorm.DB, now time.Time, limit int) ([]models.OutboxMessage, error) {
+	var results []models.OutboxMessage
+	err := tx.WithContext(ctx).Table("order_outbox").
+		Where("published_time IS NULL AND failed_time IS NULL AND next_attempt_time <= ?", now).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM order_outbox earlier
+			WHERE earlier.message_key = order_outbox.message_key AND earlier.id < order_outbox.id
+				AND earlier.published_time IS NULL AND (earlier.failed_time IS NOT NULL OR earlier.next_attempt_time > ?)
+		)`, now).
+		Order("id ASC").
+		Limit(limit).
+		Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// ClaimOutboxMessagesTx pushes the next attempt of the messages past claimUntil, so no other run picks
+// them while they are being published.
+func (r *OrderRepository) ClaimOutboxMessagesTx(ctx context.Context, tx *gorm.DB, ids []int64, claimUntil time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return tx.WithContext(ctx).Table("order_outbox").Where("id IN ?", ids).
+		Update("next_attempt_time", claimUntil).Error
+}
+
+// ReleaseOutboxClaimsTx makes messages that were claimed but not published due again, they still wait
+// behind any earlier message of their key.
+func (r *OrderRepository) ReleaseOutboxClaimsTx(ctx context.Context, tx *gorm.DB, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return tx.WithContext(ctx).Table("order_outbox").Where("id IN ?", ids).
+		Update("next_attempt_time", time.Now()).Error
+}
+
+func (r *OrderRepository) MarkOutboxPublishedTx(ctx context.Context, tx *gorm.DB, id int64) error {
+	return tx.WithContext(ctx).Table("order_outbox").Where("id = ?", id).Updates(map[string]interface{}{
+		"published_time": time.Now(),
+		"attempts":       gorm.Expr("attempts + 1"),
+		"last_error":     "",
+	}).Error
+}
+
+// MarkOutboxParkedTx gives up on the message, it stays in the outbox for someone to republish or drop.
+func (r *OrderRepository) MarkOutboxParkedTx(ctx context.Context, tx *gorm.DB, id int64, lastError string) error {
+	return tx.WithContext(ctx).Table("order_outbox").Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":    gorm.Expr("attempts + 1"),
+		"last_error":  lastError,
+		"failed_time": time.Now(),
+	}).Error
+}
+
+func (r *OrderRepository) MarkOutboxFailedTx(ctx context.Context, tx *gorm.DB, id int64, lastError string, nextAttemptTime time.Time) error {
+	return tx.WithContext(ctx).Table("order_outbox").Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":          gorm.Expr("attempts + 1"),
+		"last_error":        lastError,
+		"next_attempt_time": nextAttemptTime,
+	}).Error
+}
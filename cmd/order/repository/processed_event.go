@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"order/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MarkEventProcessedTx records the event in the ledger. It returns false when the consumer already
+// processed the event.
+func (r *OrderRepository) MarkEventProcessedTx(ctx context.Context, tx *gorm.DB, event *models.ProcessedEvent) (bool, error) {
+	result := tx.WithContext(ctx).Table("processed_events").
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"order/infrastructure/constant"
+	"order/models"
+
+	"gorm.io/gorm"
+)
+
+func (s *OrderService) GetAddresses(ctx context.Context, userID int64) ([]models.Address, error) {
+	addresses, err := s.OrderRepository.GetAddressesByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return addresses, nil
+}
+
+func (s *OrderService) GetAddress(ctx context.Context, addressID, userID int64) (models.Address, error) {
+	address, err := s.OrderRepository.GetAddressByID(ctx, addressID, userID)
+	if err != nil {
+		return models.Address{}, err
+	}
+
+	return address, nil
+}
+
+func (s *OrderService) GetDefaultAddress(ctx context.Context, userID int64) (models.Address, error) {
+	address, err := s.OrderRepository.GetDefaultAddress(ctx, userID)
+	if err != nil {
+		return models.Address{}, err
+	}
+
+	return address, nil
+}
+
+// CreateAddress saves a new address, the first address of a user becomes the default one.
+func (s *OrderService) CreateAddress(ctx context.Context, address *models.Address) error {
+	return s.OrderRepository.WithTransaction(ctx, func(tx *gorm.DB) error {
+		addresses, err := s.OrderRepository.LockAddressesByUserIDTx(ctx, tx, address.UserID)
+		if err != nil {
+			return err
+		}
+
+		if len(addresses) >= constant.MaxAddressesPerUser {
+			return constant.ErrAddressLimitReached
+		}
+
+		if len(addresses) == 0 {
+			address.IsDefault = true
+		}
+
+		err = s.OrderRepository.InsertAddressTx(ctx, tx, address)
+		if err != nil {
+			return err
+		}
+
+		if !address.IsDefault {
+			return nil
+		}
+
+		return s.OrderRepository.ClearDefaultAddressTx(ctx, tx, address.UserID, address.ID)
+	})
+}
+
+func (s *OrderService) UpdateAddress(ctx context.Context, address *models.Address) error {
+	return s.OrderRepository.WithTransaction(ctx, func(tx *gorm.DB) error {
+		addresses, err := s.OrderRepository.LockAddressesByUserIDTx(ctx, tx, address.UserID)
+		if err != nil {
+			return err
+		}
+
+		for _, saved := range addresses {
+			if saved.ID == address.ID {
+				address.CreateTime = saved.CreateTime
+			}
+		}
+
+		err = s.OrderRepository.UpdateAddressTx(ctx, tx, address)
+		if err != nil {
+			return err
+		}
+
+		if !address.IsDefault {
+			return nil
+		}
+
+		return s.OrderRepository.ClearDefaultAddressTx(ctx, tx, address.UserID, address.ID)
+	})
+}
+
+// DeleteAddress removes an address from the address book. Orders keep their own copy of the address
+// they were shipped to, so deleting it does not change them.
+func (s *OrderService) DeleteAddress(ctx context.Context, addressID, userID int64) error {
+	return s.OrderRepository.WithTransaction(ctx, func(tx *gorm.DB) error {
+		return s.OrderRepository.DeleteAddressTx(ctx, tx, addressID, userID)
+	})
+}
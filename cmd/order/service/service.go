@@ -3,32 +3,79 @@ package service
 import (
 	"context"
 	"order/cmd/order/repository"
+	"order/infrastructure/constant"
+	"order/infrastructure/product"
+	"order/infrastructure/promotion"
 	"order/models"
+	"strconv"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type OrderService struct {
 	OrderRepository *repository.OrderRepository
+	ProductClient   *product.Client
 }
 
-func NewOrderService(orderRepo *repository.OrderRepository) *OrderService {
+func NewOrderService(orderRepo *repository.OrderRepository, productClient *product.Client) *OrderService {
 	return &OrderService{
 		OrderRepository: orderRepo,
+		ProductClient:   productClient,
 	}
 }
 
-func (s *OrderService) CheckIdempotency(ctx context.Context, token string) (bool, error) {
-	isExist, err := s.OrderRepository.CheckIdempotency(ctx, token)
+func (s *OrderService) GetOrderInfoByOrderID(ctx context.Context, orderID int64) (models.Order, error) {
+	order, err := s.OrderRepository.GetOrderInfoByOrderID(ctx, orderID)
 	if err != nil {
-		return false, err
+		return models.Order{}, err
+	}
+
+	return order, nil
+}
+
+func (s *OrderService) GetOrderDetailByID(ctx context.Context, orderDetailID int64) (models.OrderDetail, error) {
+	orderDetail, err := s.OrderRepository.GetOrderDetailByID(ctx, orderDetailID)
+	if err != nil {
+		return models.OrderDetail{}, err
+	}
+
+	return orderDetail, nil
+}
+
+func (s *OrderService) GetOrderProducts(ctx context.Context, orderID int64, orderDetail models.OrderDetail) ([]models.CheckoutItem, error) {
+	products, err := s.OrderRepository.GetOrderProducts(ctx, orderID, orderDetail)
+	if err != nil {
+		return nil, err
 	}
 
-	return isExist, nil
+	return products, nil
 }
 
-func (s *OrderService) SaveIdempontency(ctx context.Context, token string) error {
-	err := s.OrderRepository.SaveIdempontency(ctx, token)
+func (s *OrderService) GetOrderStatusHistory(ctx context.Context, orderID int64, orderDetail models.OrderDetail) ([]models.StatusHistory, error) {
+	history, err := s.OrderRepository.GetOrderStatusHistory(ctx, orderID, orderDetail)
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+func (s *OrderService) GetOrderDiscounts(ctx context.Context, orderID int64) ([]models.AppliedDiscount, error) {
+	discounts, err := s.OrderRepository.GetOrderDiscounts(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return discounts, nil
+}
+
+func (s *OrderService) UpdateOrderStatus(ctx context.Context, param *models.UpdateOrderStatusParam) error {
+	err := s.OrderRepository.WithTransaction(ctx, func(tx *gorm.DB) error {
+		_, err := s.updateOrderStatusTx(ctx, tx, param)
+		return err
+	})
+
 	if err != nil {
 		return err
 	}
@@ -36,34 +83,153 @@ func (s *OrderService) SaveIdempontency(ctx context.Context, token string) error
 	return nil
 }
 
-func (s *OrderService) GetOrderInfoByOrderID(ctx context.Context, orderID int64) (models.Order, error) {
-	order, err := s.OrderRepository.GetOrderInfoByOrderID(ctx, orderID)
+// TransitionOrder moves the order to param.Status and stores the outbox messages built by events in
+// the same transaction, so the events go out only if the transition is committed.
+func (s *OrderService) TransitionOrder(ctx context.Context, param *models.UpdateOrderStatusParam, events func(order models.Order, orderDetail models.OrderDetail) ([]models.OutboxMessage, error)) error {
+	err := s.OrderRepository.WithTransaction(ctx, func(tx *gorm.DB) error {
+		return s.TransitionOrderTx(ctx, tx, param, events)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *OrderService) TransitionOrderTx(ctx context.Context, tx *gorm.DB, param *models.UpdateOrderStatusParam, events func(order models.Order, orderDetail models.OrderDetail) ([]models.OutboxMessage, error)) error {
+	order, err := s.updateOrderStatusTx(ctx, tx, param)
+	if err != nil {
+		return err
+	}
+
+	orderDetail, err := s.OrderRepository.GetOrderDetailByIDTx(ctx, tx, order.OrderDetailID)
+	if err != nil {
+		return err
+	}
+
+	messages, err := events(order, orderDetail)
+	if err != nil {
+		return err
+	}
+
+	return s.OrderRepository.InsertOutboxMessagesTx(ctx, tx, messages)
+}
+
+func (s *OrderService) UpdateOrderStatusTx(ctx context.Context, tx *gorm.DB, param *models.UpdateOrderStatusParam) error {
+	_, err := s.updateOrderStatusTx(ctx, tx, param)
+	return err
+}
+
+// updateOrderStatusTx also gives the coupons of orders that will never be paid back to their pool.
+func (s *OrderService) updateOrderStatusTx(ctx context.Context, tx *gorm.DB, param *models.UpdateOrderStatusParam) (models.Order, error) {
+	order, err := s.OrderRepository.UpdateOrderStatusTx(ctx, tx, param)
 	if err != nil {
 		return models.Order{}, err
 	}
 
+	switch param.Status {
+	case constant.OrderStatusCancelled, constant.OrderStatusExpired, constant.OrderStatusFailed:
+		err = s.OrderRepository.ReleaseCouponRedemptionsTx(ctx, tx, order.ID)
+		if err != nil {
+			return models.Order{}, err
+		}
+	}
+
 	return order, nil
 }
 
-func (s *OrderService) GetOrderDetailByID(ctx context.Context, orderDetailID int64) (models.OrderDetail, error) {
-	orderDetail, err := s.OrderRepository.GetOrderDetailByID(ctx, orderDetailID)
+// ProcessEventOnce runs fn in a transaction that also records event in the processed-events ledger.
+// When the ledger already has the event, fn is skipped and false is returned.
+func (s *OrderService) ProcessEventOnce(ctx context.Context, event *models.ProcessedEvent, fn func(tx *gorm.DB) error) (bool, error) {
+	var processed bool
+
+	err := s.OrderRepository.WithTransaction(ctx, func(tx *gorm.DB) error {
+		isNew, err := s.OrderRepository.MarkEventProcessedTx(ctx, tx, event)
+		if err != nil || !isNew {
+			return err
+		}
+
+		err = fn(tx)
+		if err != nil {
+			return err
+		}
+
+		processed = true
+		return nil
+	})
+
 	if err != nil {
-		return models.OrderDetail{}, err
+		return false, err
 	}
 
-	return orderDetail, nil
+	return processed, nil
+}
+
+// ExpireOrders moves one batch of unpaid orders to Expired, storing the outbox messages built by events
+// for each of them in the same transaction. Every order is expired in its own savepoint, so an order
+// that fails is rolled back alone and left for the next run. It returns the ids of the expired orders
+// and the error of every order that failed.
+func (s *OrderService) ExpireOrders(ctx context.Context, param *models.ExpireOrdersParam, events func(order models.Order, orderDetail models.OrderDetail) ([]models.OutboxMessage, error)) ([]int64, map[int64]error, error) {
+	var expired []int64
+	failed := map[int64]error{}
+
+	err := s.OrderRepository.WithTransaction(ctx, func(tx *gorm.DB) error {
+		orderIDs, err := s.OrderRepository.LockExpiredOrderIDsTx(ctx, tx, param)
+		if err != nil {
+			return err
+		}
+
+		for _, orderID := range orderIDs {
+			err = s.OrderRepository.WithSavepointTx(ctx, tx, func(tx *gorm.DB) error {
+				return s.expireOrderTx(ctx, tx, orderID, events)
+			})
+			if err != nil {
+				failed[orderID] = err
+				continue
+			}
+
+			expired = append(expired, orderID)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return expired, failed, nil
 }
 
-func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID int64, status int) error {
-	err := s.OrderRepository.UpdateOrderStatus(ctx, orderID, status)
+func (s *OrderService) expireOrderTx(ctx context.Context, tx *gorm.DB, orderID int64, events func(order models.Order, orderDetail models.OrderDetail) ([]models.OutboxMessage, error)) error {
+	order, err := s.updateOrderStatusTx(ctx, tx, &models.UpdateOrderStatusParam{
+		OrderID:   orderID,
+		Status:    constant.OrderStatusExpired,
+		Reason:    "payment window elapsed",
+		ActorType: constant.ActorTypeSystem,
+		ActorID:   "order-expiry",
+	})
 	if err != nil {
 		return err
 	}
 
-	return nil
+	orderDetail, err := s.OrderRepository.GetOrderDetailByIDTx(ctx, tx, order.OrderDetailID)
+	if err != nil {
+		return err
+	}
+
+	messages, err := events(order, orderDetail)
+	if err != nil {
+		return err
+	}
+
+	return s.OrderRepository.InsertOutboxMessagesTx(ctx, tx, messages)
 }
 
-func (s *OrderService) SaveOrderAndOrderDetail(ctx context.Context, order *models.Order, orderDetail *models.OrderDetail) (int64, error) {
+// SaveOrderAndOrderDetail stores the order, its items and coupon redemptions together with the outbox
+// messages built by events, so the events are published if and only if the order is committed.
+func (s *OrderService) SaveOrderAndOrderDetail(ctx context.Context, order *models.Order, orderDetail *models.OrderDetail, orderItems []models.OrderItem, redemptions []models.CouponRedemption, events func(orderID int64) ([]models.OutboxMessage, error)) (int64, error) {
 	var orderID int64
 
 	err := s.OrderRepository.WithTransaction(ctx, func(tx *gorm.DB) error {
@@ -78,6 +244,42 @@ func (s *OrderService) SaveOrderAndOrderDetail(ctx context.Context, order *model
 			return err
 		}
 
+		for index := range orderItems {
+			orderItems[index].OrderID = order.ID
+		}
+
+		err = s.OrderRepository.InsertOrderItemsTx(ctx, tx, orderItems)
+		if err != nil {
+			return err
+		}
+
+		err = s.redeemCouponsTx(ctx, tx, order, redemptions)
+		if err != nil {
+			return err
+		}
+
+		err = s.OrderRepository.InsertOrderStatusHistoryTx(ctx, tx, &models.OrderStatusHistory{
+			OrderID:    order.ID,
+			ToStatus:   order.Status,
+			ActorType:  constant.ActorTypeUser,
+			ActorID:    strconv.FormatInt(order.UserID, 10),
+			Reason:     "order placed",
+			CreateTime: order.CreateTime,
+		})
+		if err != nil {
+			return err
+		}
+
+		messages, err := events(order.ID)
+		if err != nil {
+			return err
+		}
+
+		err = s.OrderRepository.InsertOutboxMessagesTx(ctx, tx, messages)
+		if err != nil {
+			return err
+		}
+
 		orderID = order.ID
 		return nil
 	})
@@ -89,6 +291,64 @@ func (s *OrderService) SaveOrderAndOrderDetail(ctx context.Context, order *model
 	return orderID, nil
 }
 
+// redeemCouponsTx reserves the coupons for the order. The coupons stay locked until the order is
+// committed, so a limited coupon cannot be redeemed past its limit by concurrent checkouts.
+func (s *OrderService) redeemCouponsTx(ctx context.Context, tx *gorm.DB, order *models.Order, redemptions []models.CouponRedemption) error {
+	if len(redemptions) == 0 {
+		return nil
+	}
+
+	couponIDs := make([]int64, len(redemptions))
+	for index, redemption := range redemptions {
+		couponIDs[index] = redemption.CouponID
+	}
+
+	coupons, err := s.OrderRepository.LockCouponsTx(ctx, tx, couponIDs)
+	if err != nil {
+		return err
+	}
+
+	usage, err := s.OrderRepository.CountCouponRedemptionsByUserTx(ctx, tx, couponIDs, order.UserID)
+	if err != nil {
+		return err
+	}
+
+	// limits are checked again now that no other checkout can redeem these coupons
+	for _, coupon := range coupons {
+		err = promotion.Available(coupon, usage[coupon.ID], time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	for index := range redemptions {
+		redemptions[index].OrderID = order.ID
+		redemptions[index].UserID = order.UserID
+	}
+
+	return s.OrderRepository.InsertCouponRedemptionsTx(ctx, tx, redemptions)
+}
+
+// GetCoupons returns the coupons with the given codes and how many times the user redeemed each of them.
+func (s *OrderService) GetCoupons(ctx context.Context, codes []string, userID int64) ([]models.Coupon, map[int64]int, error) {
+	coupons, err := s.OrderRepository.GetCouponsByCodes(ctx, codes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	couponIDs := make([]int64, len(coupons))
+	for index, coupon := range coupons {
+		couponIDs[index] = coupon.ID
+	}
+
+	usage, err := s.OrderRepository.CountCouponRedemptionsByUser(ctx, couponIDs, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return coupons, usage, nil
+}
+
 func (s *OrderService) GetOrderHistoryByUserID(ctx context.Context, param *models.OrderHistoryParam) ([]models.OrderHistoryResponse, error) {
 	orderHistory, err := s.OrderRepository.GetOrderHistoryByUserID(ctx, param)
 	if err != nil {
@@ -98,11 +358,188 @@ func (s *OrderService) GetOrderHistoryByUserID(ctx context.Context, param *model
 	return orderHistory, nil
 }
 
-func (s *OrderService) GetProductInfo(ctx context.Context, productID int64) (models.Product, error) {
-	productInfo, err := s.OrderRepository.GetProductInfo(ctx, productID)
+func (s *OrderService) GetProductsInfo(ctx context.Context, productIDs []int64) (map[int64]models.Product, error) {
+	productsInfo, err := s.ProductClient.GetProducts(ctx, productIDs)
 	if err != nil {
-		return models.Product{}, err
+		return nil, err
+	}
+
+	return productsInfo, nil
+}
+
+// ReserveStock holds the items for the payment window. It returns no reservation when reservations are
+// turned off, the stock is then taken by the stock.update event of the order.
+func (s *OrderService) ReserveStock(ctx context.Context, referenceID string, items []models.ProductItem) (models.StockReservation, error) {
+	if !s.ProductClient.ReservationEnabled {
+		return models.StockReservation{}, nil
+	}
+
+	return s.ProductClient.ReserveStock(ctx, referenceID, items)
+}
+
+func (s *OrderService) ReleaseStockReservation(ctx context.Context, reservationID string) error {
+	return s.ProductClient.ReleaseReservation(ctx, reservationID)
+}
+
+// DrainOutbox relays one batch of pending outbox messages through publish. Messages sharing a key are
+// published strictly in insertion order: once one of them fails or is still backing off, the rest of
+// that key waits for the next run. The events of a key go to different topics and so to different
+// partitions, which kafka writes and fails one by one, so the batch is published in rounds holding at
+// most one message per key, and a key that failed sits out the later rounds. The batch is claimed in a
+// short transaction under the drain lock, published with no transaction open, and its outcome recorded
+// in a second short transaction. A run that dies in between leaves the claim to expire, so the batch is
+// published again. A message that fails param.MaxAttempts times is parked and returned, it blocks its
+// key until it is dealt with.
+func (s *OrderService) DrainOutbox(ctx context.Context, param *models.OutboxDrainParam, publish func(ctx context.Context, messages []models.OutboxMessage) []error) (int, []models.OutboxMessage, error) {
+	var batch []models.OutboxMessage
+
+	err := s.OrderRepository.WithTransaction(ctx, func(tx *gorm.DB) error {
+		locked, err := s.OrderRepository.TryLockOutboxTx(ctx, tx)
+		if err != nil || !locked {
+			return err
+		}
+
+		now := time.Now()
+		batch, err = s.OrderRepository.GetPendingOutboxMessagesTx(ctx, tx, now, param.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		ids := make([]int64, len(batch))
+		for index, message := range batch {
+			ids[index] = message.ID
+		}
+
+		return s.OrderRepository.ClaimOutboxMessagesTx(ctx, tx, ids, now.Add(param.ClaimTTL))
+	})
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if len(batch) == 0 {
+		return 0, nil, nil
+	}
+
+	results := make([]error, len(batch))
+	attempted := make([]bool, len(batch))
+	failedKeys := map[string]bool{}
+
+	for _, round := range outboxRounds(batch) {
+		// a relay shutting down leaves the rest of the batch for the next run
+		if ctx.Err() != nil {
+			break
+		}
+
+		indexes := make([]int, 0, len(round))
+		messages := make([]models.OutboxMessage, 0, len(round))
+		for _, index := range round {
+			if !failedKeys[batch[index].MessageKey] {
+				indexes = append(indexes, index)
+				messages = append(messages, batch[index])
+			}
+		}
+
+		if len(messages) == 0 {
+			continue
+		}
+
+		roundResults := publish(ctx, messages)
+		for position, index := range indexes {
+			attempted[index] = true
+
+			if roundResults != nil && roundResults[position] != nil {
+				results[index] = roundResults[position]
+				failedKeys[batch[index].MessageKey] = true
+			}
+		}
+	}
+
+	var published int
+	var parked []models.OutboxMessage
+	var unattempted []int64
+
+	// the batch is out, its outcome is recorded even if the relay is shutting down
+	storeCtx := context.WithoutCancel(ctx)
+	err = s.OrderRepository.WithTransaction(storeCtx, func(tx *gorm.DB) error {
+		for index, message := range batch {
+			if !attempted[index] {
+				unattempted = append(unattempted, message.ID)
+				continue
+			}
+
+			publishErr := results[index]
+			if publishErr != nil {
+				message.Attempts++
+				message.LastError = publishErr.Error()
+
+				if message.Attempts >= param.MaxAttempts {
+					err := s.OrderRepository.MarkOutboxParkedTx(storeCtx, tx, message.ID, message.LastError)
+					if err != nil {
+						return err
+					}
+
+					parked = append(parked, message)
+					continue
+				}
+
+				nextAttemptTime := time.Now().Add(outboxBackoff(param, message.Attempts))
+
+				err := s.OrderRepository.MarkOutboxFailedTx(storeCtx, tx, message.ID, message.LastError, nextAttemptTime)
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			err := s.OrderRepository.MarkOutboxPublishedTx(storeCtx, tx, message.ID)
+			if err != nil {
+				return err
+			}
+
+			published++
+		}
+
+		return s.OrderRepository.ReleaseOutboxClaimsTx(storeCtx, tx, unattempted)
+	})
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return published, parked, nil
+}
+
+// outboxRounds splits the batch into rounds of indexes, the n-th round holding the n-th message of
+// every key that has one, so a round never holds two messages of a key.
+func outboxRounds(batch []models.OutboxMessage) [][]int {
+	var rounds [][]int
+	position := map[string]int{}
+
+	for index, message := range batch {
+		round := position[message.MessageKey]
+		position[message.MessageKey]++
+
+		if round == len(rounds) {
+			rounds = append(rounds, nil)
+		}
+
+		rounds[round] = append(rounds[round], index)
+	}
+
+	return rounds
+}
+
+func outboxBackoff(param *models.OutboxDrainParam, attempt int) time.Duration {
+	backoff := param.RetryBackoff
+	for i := 1; i < attempt && backoff < param.MaxRetryBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > param.MaxRetryBackoff {
+		backoff = param.MaxRetryBackoff
 	}
 
-	return productInfo, nil
+	return backoff
 }
@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"order/infrastructure/address"
+	"order/infrastructure/constant"
+	"order/infrastructure/tax"
+	"order/models"
+	"strings"
+	"time"
+)
+
+func (uc *OrderUsecase) GetAddresses(ctx context.Context, userID int64) ([]models.Address, error) {
+	addresses, err := uc.OrderService.GetAddresses(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if addresses == nil {
+		addresses = []models.Address{}
+	}
+
+	return addresses, nil
+}
+
+func (uc *OrderUsecase) CreateAddress(ctx context.Context, userID int64, request models.AddressRequest) (models.Address, error) {
+	shippingAddress := address.FromRequest(userID, request)
+	err := address.Validate(shippingAddress)
+	if err != nil {
+		return models.Address{}, err
+	}
+
+	shippingAddress.CreateTime = time.Now()
+	shippingAddress.UpdateTime = shippingAddress.CreateTime
+
+	err = uc.OrderService.CreateAddress(ctx, &shippingAddress)
+	if err != nil {
+		return models.Address{}, err
+	}
+
+	return shippingAddress, nil
+}
+
+// UpdateAddress replaces every field of a saved address.
+func (uc *OrderUsecase) UpdateAddress(ctx context.Context, param *models.AddressParam, request models.AddressRequest) (models.Address, error) {
+	shippingAddress := address.FromRequest(param.UserID, request)
+	shippingAddress.ID = param.AddressID
+
+	err := address.Validate(shippingAddress)
+	if err != nil {
+		return models.Address{}, err
+	}
+
+	shippingAddress.UpdateTime = time.Now()
+
+	err = uc.OrderService.UpdateAddress(ctx, &shippingAddress)
+	if err != nil {
+		return models.Address{}, err
+	}
+
+	return shippingAddress, nil
+}
+
+func (uc *OrderUsecase) DeleteAddress(ctx context.Context, param *models.AddressParam) error {
+	return uc.OrderService.DeleteAddress(ctx, param.AddressID, param.UserID)
+}
+
+// resolveShippingAddress fills the free-text address and destination of the checkout from the saved
+// address it references, or from the user's default address when it names none. A checkout that
+// still sends only a free-text address keeps it, but the destination it sends must follow the rules
+// of its country, since it decides the tax and shipping charged. When tax is charged, a checkout whose
+// destination country does not resolve is rejected, it would otherwise go untaxed.
+func (uc *OrderUsecase) resolveShippingAddress(ctx context.Context, param *models.CheckoutRequest) (*models.Address, error) {
+	var shippingAddress models.Address
+	var err error
+
+	switch {
+	case param.ShippingAddressID > 0:
+		shippingAddress, err = uc.OrderService.GetAddress(ctx, param.ShippingAddressID, param.UserID)
+		if errors.Is(err, constant.ErrAddressNotFound) {
+			return nil, invalidCheckout("Unknown shipping address %d", param.ShippingAddressID)
+		}
+	case param.ShippingAddress == "":
+		shippingAddress, err = uc.OrderService.GetDefaultAddress(ctx, param.UserID)
+		if errors.Is(err, constant.ErrAddressNotFound) {
+			return nil, uc.requireDestination()
+		}
+	default:
+		if strings.TrimSpace(param.ShippingCountry) == "" {
+			return nil, uc.requireDestination()
+		}
+
+		param.ShippingCountry = strings.ToUpper(strings.TrimSpace(param.ShippingCountry))
+		param.ShippingRegion = strings.ToUpper(strings.TrimSpace(param.ShippingRegion))
+
+		err = address.ValidateDestination(param.ShippingCountry, param.ShippingRegion)
+		if err != nil {
+			return nil, invalidCheckout("Invalid shipping destination: %s", err.Error())
+		}
+
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	param.ShippingAddress = address.Format(shippingAddress)
+	param.ShippingCountry = shippingAddress.Country
+	param.ShippingRegion = shippingAddress.Region
+
+	return &shippingAddress, nil
+}
+
+// requireDestination rejects a checkout with no destination country unless no tax is charged at all.
+func (uc *OrderUsecase) requireDestination() error {
+	if _, isNoTax := uc.TaxCalculator.(tax.NoTax); isNoTax {
+		return nil
+	}
+
+	return invalidCheckout("A shipping country is required to calculate tax")
+}
@@ -0,0 +1,367 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"order/infrastructure/cart"
+	"order/infrastructure/constant"
+	"order/models"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GetCart prices the cart against product service in the requested currency. Items keep the last price
+// the user saw, so a price that moved since then is flagged.
+func (uc *OrderUsecase) GetCart(ctx context.Context, param *models.CartParam) (models.CartResponse, error) {
+	current, err := uc.loadCart(ctx, param)
+	if err != nil {
+		return models.CartResponse{}, err
+	}
+
+	return uc.priceCart(ctx, current, param.Currency)
+}
+
+// CreateCart starts a guest cart, or returns the cart of a signed-in user, who only ever has one.
+// Concurrent calls for the same user all get the cart the first of them created.
+func (uc *OrderUsecase) CreateCart(ctx context.Context, param *models.CartParam) (models.CartResponse, error) {
+	if param.UserID != 0 {
+		current, err := uc.CartStore.GetByUserID(ctx, param.UserID)
+		if err == nil {
+			return uc.priceCart(ctx, current, param.Currency)
+		}
+
+		if !errors.Is(err, cart.ErrCartNotFound) {
+			return models.CartResponse{}, err
+		}
+	}
+
+	now := time.Now()
+	current := models.Cart{
+		ID:         uuid.NewString(),
+		UserID:     param.UserID,
+		Items:      []models.CartItem{},
+		CreateTime: now,
+		UpdateTime: now,
+	}
+
+	err := uc.CartStore.Create(ctx, current)
+	if errors.Is(err, cart.ErrCartExists) {
+		current, err = uc.CartStore.GetByUserID(ctx, param.UserID)
+	}
+
+	if err != nil {
+		return models.CartResponse{}, err
+	}
+
+	return uc.priceCart(ctx, current, param.Currency)
+}
+
+// AddCartItem puts a product in the cart, adding to the quantity already there.
+func (uc *OrderUsecase) AddCartItem(ctx context.Context, param *models.CartParam, request models.CartItemRequest) (models.CartResponse, error) {
+	return uc.changeCart(ctx, param, func(current models.Cart) (models.Cart, error) {
+		quantity := request.Quantity
+		for _, item := range current.Items {
+			if item.ProductID == request.ProductID {
+				quantity += item.Quantity
+			}
+		}
+
+		return uc.setCartItem(ctx, current, request.ProductID, quantity)
+	})
+}
+
+// UpdateCartItem sets the quantity of a product in the cart, zero removes it.
+func (uc *OrderUsecase) UpdateCartItem(ctx context.Context, param *models.CartParam, request models.CartItemRequest) (models.CartResponse, error) {
+	return uc.changeCart(ctx, param, func(current models.Cart) (models.Cart, error) {
+		return uc.setCartItem(ctx, current, request.ProductID, request.Quantity)
+	})
+}
+
+func (uc *OrderUsecase) RemoveCartItem(ctx context.Context, param *models.CartParam, productID int64) (models.CartResponse, error) {
+	return uc.UpdateCartItem(ctx, param, models.CartItemRequest{ProductID: productID})
+}
+
+// MergeCart moves the items of a guest cart into the cart of the user who just signed in, adding up
+// the quantities of products found in both. The guest cart is gone afterwards.
+func (uc *OrderUsecase) MergeCart(ctx context.Context, param *models.CartParam, guestCartID string) (models.CartResponse, error) {
+	for attempt := 1; ; attempt++ {
+		response, err := uc.mergeCart(ctx, param, guestCartID)
+
+		// a cart changed or created since it was read, merge again with fresh copies
+		if (errors.Is(err, cart.ErrCartConflict) || errors.Is(err, cart.ErrCartExists)) && attempt < constant.CartSaveAttempts {
+			continue
+		}
+
+		return response, err
+	}
+}
+
+func (uc *OrderUsecase) mergeCart(ctx context.Context, param *models.CartParam, guestCartID string) (models.CartResponse, error) {
+	guest, err := uc.CartStore.Get(ctx, guestCartID)
+	if err != nil {
+		return models.CartResponse{}, err
+	}
+
+	// only a guest cart can be merged, a cart that belongs to someone is reported as not found
+	if guest.UserID != 0 && guest.UserID != param.UserID {
+		return models.CartResponse{}, cart.ErrCartNotFound
+	}
+
+	current, err := uc.CartStore.GetByUserID(ctx, param.UserID)
+	if errors.Is(err, cart.ErrCartNotFound) {
+		// the guest cart becomes the user's cart
+		guest.UserID = param.UserID
+		guest.UpdateTime = time.Now()
+
+		return uc.saveCart(ctx, guest, param.Currency)
+	}
+
+	if err != nil {
+		return models.CartResponse{}, err
+	}
+
+	if current.ID == guest.ID {
+		return uc.priceCart(ctx, current, param.Currency)
+	}
+
+	for _, guestItem := range guest.Items {
+		merged := false
+		for index, item := range current.Items {
+			if item.ProductID == guestItem.ProductID {
+				current.Items[index].Quantity = min(item.Quantity+guestItem.Quantity, constant.MaxCartItemQuantity)
+				merged = true
+			}
+		}
+
+		if !merged && len(current.Items) < constant.MaxCartItems {
+			current.Items = append(current.Items, guestItem)
+		}
+	}
+
+	current.UpdateTime = time.Now()
+	response, err := uc.saveCart(ctx, current, param.Currency)
+	if err != nil {
+		return models.CartResponse{}, err
+	}
+
+	// a guest cart changed while it was merged is kept, so what was added to it is not lost
+	err = uc.CartStore.Delete(ctx, guest)
+	if err != nil && !errors.Is(err, cart.ErrCartConflict) {
+		return models.CartResponse{}, err
+	}
+
+	return response, nil
+}
+
+// loadCart returns the cart if the caller may use it: a user's cart is only theirs, a guest cart
+// belongs to whoever knows its id.
+func (uc *OrderUsecase) loadCart(ctx context.Context, param *models.CartParam) (models.Cart, error) {
+	current, err := uc.CartStore.Get(ctx, param.CartID)
+	if err != nil {
+		return models.Cart{}, err
+	}
+
+	if current.UserID != 0 && current.UserID != param.UserID {
+		return models.Cart{}, cart.ErrCartNotFound
+	}
+
+	return current, nil
+}
+
+// changeCart applies change to the cart and saves it. When another request saved the cart in between,
+// the change is made again on a fresh copy so neither write is lost.
+func (uc *OrderUsecase) changeCart(ctx context.Context, param *models.CartParam, change func(current models.Cart) (models.Cart, error)) (models.CartResponse, error) {
+	for attempt := 1; ; attempt++ {
+		current, err := uc.loadCart(ctx, param)
+		if err != nil {
+			return models.CartResponse{}, err
+		}
+
+		current, err = change(current)
+		if err != nil {
+			return models.CartResponse{}, err
+		}
+
+		response, err := uc.saveCart(ctx, current, param.Currency)
+		if errors.Is(err, cart.ErrCartConflict) && attempt < constant.CartSaveAttempts {
+			continue
+		}
+
+		return response, err
+	}
+}
+
+func (uc *OrderUsecase) setCartItem(ctx context.Context, current models.Cart, productID int64, quantity int) (models.Cart, error) {
+	if quantity < 0 || quantity > constant.MaxCartItemQuantity {
+		return models.Cart{}, invalidCart("invalid quantity product %d, maximum is %d", productID, constant.MaxCartItemQuantity)
+	}
+
+	items := make([]models.CartItem, 0, len(current.Items)+1)
+	for _, item := range current.Items {
+		if item.ProductID != productID {
+			items = append(items, item)
+		}
+	}
+
+	if quantity > 0 {
+		if len(items) >= constant.MaxCartItems {
+			return models.Cart{}, invalidCart("a cart holds at most %d products", constant.MaxCartItems)
+		}
+
+		productsInfo, err := uc.OrderService.GetProductsInfo(ctx, []int64{productID})
+		if err != nil {
+			return models.Cart{}, fmt.Errorf("Failed get product info, err : %w", err)
+		}
+
+		productInfo := productsInfo[productID]
+		if quantity > productInfo.Stock {
+			return models.Cart{}, invalidCart("Invalid product quantity %d, stock left %d", productID, productInfo.Stock)
+		}
+
+		items = append(items, models.CartItem{
+			ProductID:   productID,
+			ProductName: productInfo.Name,
+			Quantity:    quantity,
+			UnitPrice:   productInfo.Price,
+		})
+	}
+
+	current.Items = items
+	current.UpdateTime = time.Now()
+
+	return current, nil
+}
+
+// priceCart re-prices every item with what product service charges now without touching the stored
+// cart, reading a cart must not race the writes made to it.
+func (uc *OrderUsecase) priceCart(ctx context.Context, current models.Cart, currency string) (models.CartResponse, error) {
+	response, _, err := uc.repriceCart(ctx, current, currency)
+	return response, err
+}
+
+// saveCart prices the cart and saves it with the new prices, so the next change is measured from what
+// the user was just shown. ErrCartConflict means another request saved the cart first.
+func (uc *OrderUsecase) saveCart(ctx context.Context, current models.Cart, currency string) (models.CartResponse, error) {
+	response, priced, err := uc.repriceCart(ctx, current, currency)
+	if err != nil {
+		return models.CartResponse{}, err
+	}
+
+	_, err = uc.CartStore.Save(ctx, priced)
+	if err != nil {
+		return models.CartResponse{}, err
+	}
+
+	return response, nil
+}
+
+// repriceCart returns the response of the cart and a copy of it holding the prices and names product
+// service has now.
+func (uc *OrderUsecase) repriceCart(ctx context.Context, current models.Cart, currency string) (models.CartResponse, models.Cart, error) {
+	quote, err := uc.quoteCurrency(ctx, currency)
+	if err != nil {
+		return models.CartResponse{}, models.Cart{}, err
+	}
+
+	response := models.CartResponse{
+		CartID:   current.ID,
+		Currency: quote.Currency,
+		Items:    make([]models.CartItemResponse, 0, len(current.Items)),
+	}
+
+	if len(current.Items) == 0 {
+		return response, current, nil
+	}
+
+	productIDs := make([]int64, len(current.Items))
+	for index, item := range current.Items {
+		productIDs[index] = item.ProductID
+	}
+
+	productsInfo, err := uc.OrderService.GetProductsInfo(ctx, productIDs)
+	if err != nil {
+		return models.CartResponse{}, models.Cart{}, fmt.Errorf("Failed get product info, err : %w", err)
+	}
+
+	priced := current
+	priced.Items = slices.Clone(current.Items)
+
+	for index, item := range current.Items {
+		productInfo := productsInfo[item.ProductID]
+
+		unitPrice, err := productInfo.Price.ConvertTo(quote.Rate.Inverse(), quote.Currency)
+		if err != nil {
+			return models.CartResponse{}, models.Cart{}, invalidCart("price of product %d is too large", item.ProductID)
+		}
+
+		lineTotal, err := unitPrice.Mul(int64(item.Quantity))
+		if err != nil {
+			return models.CartResponse{}, models.Cart{}, invalidCart("total of product %d is too large", item.ProductID)
+		}
+
+		response.Items = append(response.Items, models.CartItemResponse{
+			ProductID:    item.ProductID,
+			ProductName:  productInfo.Name,
+			Quantity:     item.Quantity,
+			UnitPrice:    unitPrice,
+			LineTotal:    lineTotal,
+			PriceChanged: item.UnitPrice != productInfo.Price,
+			Available:    item.Quantity <= productInfo.Stock,
+		})
+
+		response.TotalQty += item.Quantity
+		response.Subtotal, err = response.Subtotal.Add(lineTotal)
+		if err != nil {
+			return models.CartResponse{}, models.Cart{}, invalidCart("cart total is too large")
+		}
+
+		priced.Items[index].UnitPrice = productInfo.Price
+		priced.Items[index].ProductName = productInfo.Name
+	}
+
+	return response, priced, nil
+}
+
+// checkoutItemsFromCart turns the cart into checkout items priced by product service in the charged
+// currency, the client's idea of the prices does not matter.
+func (uc *OrderUsecase) checkoutItemsFromCart(ctx context.Context, param *models.CheckoutRequest) (models.Cart, []models.CheckoutItem, error) {
+	current, err := uc.loadCart(ctx, &models.CartParam{UserID: param.UserID, CartID: param.CartID})
+	if err != nil {
+		if errors.Is(err, cart.ErrCartNotFound) {
+			return models.Cart{}, nil, invalidCheckout("Unknown cart %s", param.CartID)
+		}
+
+		return models.Cart{}, nil, err
+	}
+
+	if len(current.Items) == 0 {
+		return models.Cart{}, nil, invalidCheckout("Cart %s is empty", param.CartID)
+	}
+
+	priced, err := uc.priceCart(ctx, current, param.Currency)
+	if err != nil {
+		if errors.Is(err, constant.ErrInvalidCart) {
+			return models.Cart{}, nil, invalidCheckout("%s", err.Error())
+		}
+
+		return models.Cart{}, nil, err
+	}
+
+	items := make([]models.CheckoutItem, len(priced.Items))
+	for index, item := range priced.Items {
+		items[index] = models.CheckoutItem{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			Price:     item.UnitPrice,
+		}
+	}
+
+	return current, items, nil
+}
+
+func invalidCart(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", constant.ErrInvalidCart, fmt.Sprintf(format, args...))
+}
@@ -2,54 +2,212 @@ package usecase
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"order/cmd/order/service"
+	"order/config"
+	"order/infrastructure/cart"
 	"order/infrastructure/constant"
+	"order/infrastructure/exchangerate"
+	"order/infrastructure/idempotency"
 	"order/infrastructure/log"
+	"order/infrastructure/money"
+	"order/infrastructure/product"
+	"order/infrastructure/promotion"
+	"order/infrastructure/shipping"
+	"order/infrastructure/tax"
 	"order/kafka"
 	"order/models"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
 type OrderUsecase struct {
-	OrderService  *service.OrderService
-	KafkaProducer *kafka.KafkaProducer
+	OrderService       *service.OrderService
+	IdempotencyStore   idempotency.Store
+	CartStore          cart.Store
+	IdempotencyTTL     time.Duration
+	IdempotencyLockTTL time.Duration
+	RateProvider       exchangerate.Provider
+	TaxCalculator      tax.Calculator
+	ShippingProvider   shipping.Provider
+	DefaultItemWeight  int64
 }
 
-func NewOrderUsecase(orderService *service.OrderService, kafkaProducer *kafka.KafkaProducer) *OrderUsecase {
-	return &OrderUsecase{
-		OrderService:  orderService,
-		KafkaProducer: kafkaProducer,
+func NewOrderUsecase(orderService *service.OrderService, idempotencyStore idempotency.Store, idempotencyCfg config.IdempotencyConfig, rateProvider exchangerate.Provider, taxCalculator tax.Calculator, shippingProvider shipping.Provider, shippingCfg config.ShippingConfig, cartStore cart.Store) *OrderUsecase {
+	uc := &OrderUsecase{
+		OrderService:       orderService,
+		IdempotencyStore:   idempotencyStore,
+		CartStore:          cartStore,
+		RateProvider:       rateProvider,
+		TaxCalculator:      taxCalculator,
+		ShippingProvider:   shippingProvider,
+		DefaultItemWeight:  shippingCfg.DefaultItemWeight,
+		IdempotencyTTL:     idempotencyCfg.TTL,
+		IdempotencyLockTTL: idempotencyCfg.LockTTL,
 	}
+
+	if uc.IdempotencyTTL <= 0 {
+		uc.IdempotencyTTL = idempotency.DefaultTTL
+	}
+
+	if uc.IdempotencyLockTTL <= 0 {
+		uc.IdempotencyLockTTL = idempotency.DefaultLockTTL
+	}
+
+	return uc
+}
+
+// CheckoutOrder creates the order once per idempotency token. A retry of a finished checkout replays
+// the original order id, a retry while the first request is still running gets ErrIdempotencyInFlight.
+func (uc *OrderUsecase) CheckoutOrder(ctx context.Context, param *models.CheckoutRequest) (models.CheckoutResponse, error) {
+	if param.IdempontencyToken == "" {
+		orderID, err := uc.checkout(ctx, param)
+		if err != nil {
+			return models.CheckoutResponse{}, err
+		}
+
+		return models.CheckoutResponse{OrderID: orderID}, nil
+	}
+
+	key := idempotency.CheckoutKey(param.UserID, param.IdempontencyToken)
+	record, reserved, err := uc.IdempotencyStore.Reserve(ctx, key, uc.IdempotencyLockTTL)
+	if err != nil {
+		return models.CheckoutResponse{}, err
+	}
+
+	if !reserved {
+		if record.Status == idempotency.StatusCompleted {
+			return models.CheckoutResponse{OrderID: record.OrderID, Replayed: true}, nil
+		}
+
+		return models.CheckoutResponse{}, constant.ErrIdempotencyInFlight
+	}
+
+	// the request context may already be done, the key must still be settled
+	storeCtx := context.WithoutCancel(ctx)
+
+	orderID, err := uc.checkout(ctx, param)
+	if err != nil {
+		// free the key so the client can retry with the same token
+		if releaseErr := uc.IdempotencyStore.Release(storeCtx, key, record.Owner); releaseErr != nil {
+			log.Logger.WithFields(logrus.Fields{
+				"err": releaseErr.Error(),
+				"key": key,
+			}).Error("uc.IdempotencyStore.Release() got error")
+		}
+
+		return models.CheckoutResponse{}, err
+	}
+
+	err = uc.IdempotencyStore.Complete(storeCtx, key, record.Owner, idempotency.Record{
+		Status:  idempotency.StatusCompleted,
+		OrderID: orderID,
+	}, uc.IdempotencyTTL)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"err":      err.Error(),
+			"key":      key,
+			"order_id": orderID,
+		}).Error("uc.IdempotencyStore.Complete() got error")
+	}
+
+	return models.CheckoutResponse{OrderID: orderID}, nil
 }
 
-func (uc *OrderUsecase) CheckoutOrder(ctx context.Context, param *models.CheckoutRequest) (int64, error) {
+func (uc *OrderUsecase) checkout(ctx context.Context, param *models.CheckoutRequest) (int64, error) {
 	var orderID int64
 
-	// check idempotency token
-	if param.IdempontencyToken != "" {
-		isExist, err := uc.OrderService.CheckIdempotency(ctx, param.IdempontencyToken)
+	// a saved address decides where the order ships to
+	shippingAddress, err := uc.resolveShippingAddress(ctx, param)
+	if err != nil {
+		return 0, err
+	}
+
+	// a cart decides what is bought and at which price
+	var checkoutCart *models.Cart
+	if param.CartID != "" {
+		if len(param.Items) > 0 {
+			return 0, invalidCheckout("Send either items or a cart, not both")
+		}
+
+		current, items, err := uc.checkoutItemsFromCart(ctx, param)
 		if err != nil {
 			return 0, err
 		}
 
-		if isExist {
-			return 0, errors.New("Order already created, please check again!")
-		}
+		checkoutCart = &current
+		param.Items = items
+	}
+
+	// snapshot the rate the order is charged with
+	quote, err := uc.quoteCurrency(ctx, param.Currency)
+	if err != nil {
+		return 0, err
 	}
 
 	// validate product
-	err := uc.validateProduct(ctx, param.Items)
+	productsInfo, err := uc.validateProduct(ctx, param.Items, quote)
+	if err != nil {
+		return 0, err
+	}
+
+	// price every line, then the order
+	orderItems, err := uc.constructOrderItems(param.Items, productsInfo)
+	if err != nil {
+		return 0, err
+	}
+
+	totalQty, subtotal, err := uc.calculateOrderSummary(orderItems)
+	if err != nil {
+		return 0, err
+	}
+
+	breakdown, err := uc.applyCoupons(ctx, param, orderItems, subtotal, quote)
+	if err != nil {
+		return 0, err
+	}
+
+	taxResult, err := uc.calculateTax(ctx, param, orderItems, breakdown.DiscountAmount, quote)
 	if err != nil {
 		return 0, err
 	}
 
-	// calculate product amount
-	totalQty, totalAmount := uc.calculateOrderSummary(param.Items)
+	shippingOption, err := uc.selectShipping(ctx, param, productsInfo, quote)
+	if err != nil {
+		return 0, err
+	}
+
+	// an inclusive tax is already part of the price, shipping is not taxed
+	total := breakdown.Total
+	if taxResult.Mode == tax.ModeExclusive {
+		total, err = total.Add(taxResult.Total)
+		if err != nil {
+			return 0, invalidCheckout("order total is too large")
+		}
+	}
+
+	total, err = total.Add(shippingOption.Fee)
+	if err != nil {
+		return 0, invalidCheckout("order total is too large")
+	}
+
+	baseAmount, err := total.ConvertTo(quote.Rate, quote.Base)
+	if err != nil {
+		return 0, invalidCheckout("order total is too large")
+	}
+
+	// hold the stock until the order is paid, so two buyers of the last units cannot both get them
+	reservation, err := uc.reserveStock(ctx, param.Items)
+	if err != nil {
+		return 0, err
+	}
 
 	// construct order detail
 	products, orderHistory := uc.constructOrderDetail(param.Items)
@@ -61,120 +219,430 @@ func (uc *OrderUsecase) CheckoutOrder(ctx context.Context, param *models.Checkou
 	}
 
 	order := models.Order{
-		UserID:          param.UserID,
-		Amount:          totalAmount,
-		TotalQty:        totalQty,
-		Status:          constant.OrderStatusCreated,
-		PaymentMethod:   param.PaymentMethod,
-		ShippingAddress: param.ShippingAddress,
+		UserID:                param.UserID,
+		Subtotal:              breakdown.Subtotal,
+		DiscountAmount:        breakdown.DiscountAmount,
+		TaxAmount:             taxResult.Total,
+		TaxMode:               taxResult.Mode,
+		Amount:                total,
+		Currency:              quote.Currency,
+		BaseAmount:            baseAmount,
+		BaseCurrency:          quote.Base,
+		ExchangeRate:          quote.Rate,
+		ExchangeRateTime:      quote.Time,
+		TotalQty:              totalQty,
+		Status:                constant.OrderStatusCreated,
+		PaymentMethod:         param.PaymentMethod,
+		ShippingAddress:       param.ShippingAddress,
+		ShippingAddressDetail: shippingAddress,
+		ShippingCountry:       strings.ToUpper(strings.TrimSpace(param.ShippingCountry)),
+		ShippingRegion:        strings.ToUpper(strings.TrimSpace(param.ShippingRegion)),
+		ShippingMethod:        shippingOption.Method,
+		ShippingCarrier:       shippingOption.Carrier,
+		ShippingFee:           shippingOption.Fee,
+		StockReservationID:    reservation.ID,
+		CreateTime:            time.Now(),
+		UpdateTime:            time.Now(),
+	}
+
+	if reservation.ID != "" {
+		order.StockReservationExpireTime = &reservation.ExpireTime
 	}
 
-	orderID, err = uc.OrderService.SaveOrderAndOrderDetail(ctx, &order, &orderDetail)
+	orderID, err = uc.OrderService.SaveOrderAndOrderDetail(ctx, &order, &orderDetail, orderItems, breakdown.Redemptions, func(orderID int64) ([]models.OutboxMessage, error) {
+		return uc.constructCheckoutEvents(ctx, orderID, param, &order, orderItems)
+	})
 	if err != nil {
+		uc.releaseStock(ctx, reservation.ID)
 		return 0, err
 	}
 
-	// save idempontecy token
-	if param.IdempontencyToken != "" {
-		err = uc.OrderService.SaveIdempontency(ctx, param.IdempontencyToken)
-		if err != nil {
+	// the order is placed, a cart left behind only means the user has to empty it. A cart changed
+	// during checkout is kept, what was added to it is not part of the order.
+	if checkoutCart != nil {
+		err = uc.CartStore.Delete(context.WithoutCancel(ctx), *checkoutCart)
+		if err != nil && !errors.Is(err, cart.ErrCartConflict) {
 			log.Logger.WithFields(logrus.Fields{
-				"err":   err.Error(),
-				"token": param.IdempontencyToken,
-			}).Info("uc.OrderService.SaveIdempotency() got error")
+				"err":      err.Error(),
+				"cart_id":  checkoutCart.ID,
+				"order_id": orderID,
+			}).Error("uc.CartStore.Delete() got error")
 		}
 	}
 
-	// publish to payment service
-	orderCreatedEvent := models.OrderCreatedEvent{
-		OrderID:         orderID,
-		UserID:          param.UserID,
-		TotalAmount:     totalAmount,
-		TotalQty:        totalQty,
-		PaymentMethod:   param.PaymentMethod,
-		ShippingAddress: param.ShippingAddress,
-	}
+	return orderID, nil
+}
 
-	go func(ctx context.Context) {
-		if err := uc.KafkaProducer.PublishOrderCreated(ctx, orderCreatedEvent); err != nil {
-			fmt.Println("Failed publish order created event:", err)
-		} else {
-			fmt.Println("Successfully publish order created event for Order ID:", orderID)
+// constructCheckoutEvents builds the events published to payment and product service once the order is committed.
+func (uc *OrderUsecase) constructCheckoutEvents(ctx context.Context, orderID int64, param *models.CheckoutRequest, order *models.Order, orderItems []models.OrderItem) ([]models.OutboxMessage, error) {
+	lines := make([]models.OrderLine, len(orderItems))
+	for index, item := range orderItems {
+		lines[index] = models.OrderLine{
+			ProductID:      item.ProductID,
+			Quantity:       item.Quantity,
+			UnitPrice:      item.UnitPrice,
+			LineTotal:      item.LineTotal,
+			DiscountAmount: item.DiscountAmount,
+			TaxRateBps:     item.TaxRateBps,
+			TaxAmount:      item.TaxAmount,
 		}
-	}(context.Background())
+	}
+
+	orderCreated, err := kafka.NewOutboxMessage(ctx, constant.TopicOrderCreated, orderID, models.OrderCreatedEvent{
+		OrderID:               orderID,
+		UserID:                param.UserID,
+		Subtotal:              order.Subtotal,
+		DiscountAmount:        order.DiscountAmount,
+		TaxAmount:             order.TaxAmount,
+		TaxMode:               order.TaxMode,
+		ShippingMethod:        order.ShippingMethod,
+		ShippingCarrier:       order.ShippingCarrier,
+		ShippingFee:           order.ShippingFee,
+		TotalAmount:           order.Amount,
+		Currency:              order.Currency,
+		BaseAmount:            order.BaseAmount,
+		BaseCurrency:          order.BaseCurrency,
+		ExchangeRate:          order.ExchangeRate,
+		TotalQty:              order.TotalQty,
+		PaymentMethod:         param.PaymentMethod,
+		ShippingAddress:       param.ShippingAddress,
+		ShippingAddressDetail: order.ShippingAddressDetail,
+		Items:                 lines,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// reserved stock is already taken, only an order placed without a reservation takes it by event
+	if order.StockReservationID != "" {
+		return []models.OutboxMessage{orderCreated}, nil
+	}
 
-	updateStockEvent := models.ProductStockUpdateEvent{
+	stockUpdate, err := kafka.NewOutboxMessage(ctx, constant.TopicProductStockUpdate, orderID, models.ProductStockUpdateEvent{
 		OrderID:   orderID,
-		Products:  convertCheckoutItemToProductItems(param.Items),
+		Products:  kafka.ProductItemsFromCheckoutItems(param.Items),
 		EventTime: time.Now(),
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	go func(ctx context.Context) {
-		if err := uc.KafkaProducer.PublishProductStockUpdate(ctx, updateStockEvent); err != nil {
-			fmt.Println("Failed publish product stock update event:", err)
-		} else {
-			fmt.Println("Successfully publish product stock update event for Order ID:", orderID)
+	return []models.OutboxMessage{orderCreated, stockUpdate}, nil
+}
+
+// reserveStock holds the stock of every item at product service. The stock read by validateProduct
+// may be gone by now, so a reservation that cannot be met fails the checkout.
+func (uc *OrderUsecase) reserveStock(ctx context.Context, items []models.CheckoutItem) (models.StockReservation, error) {
+	reservation, err := uc.OrderService.ReserveStock(ctx, uuid.NewString(), kafka.ProductItemsFromCheckoutItems(items))
+	if err != nil {
+		if errors.Is(err, product.ErrInsufficientStock) {
+			return models.StockReservation{}, invalidCheckout("Not enough stock left for the order")
 		}
-	}(context.Background())
 
-	return orderID, nil
+		return models.StockReservation{}, fmt.Errorf("Failed reserve stock, err : %w", err)
+	}
+
+	return reservation, nil
 }
 
-func convertCheckoutItemToProductItems(items []models.CheckoutItem) []models.ProductItem {
-	result := make([]models.ProductItem, 0, len(items))
+// releaseStock gives back the stock of an order that was not saved. A failed release only holds the
+// stock until the reservation expires.
+func (uc *OrderUsecase) releaseStock(ctx context.Context, reservationID string) {
+	if reservationID == "" {
+		return
+	}
 
-	for index, item := range items {
-		result[index] = models.ProductItem{
-			ProductID: item.ProductID,
-			Qty:       item.Quantity,
-		}
+	err := uc.OrderService.ReleaseStockReservation(context.WithoutCancel(ctx), reservationID)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"err":            err.Error(),
+			"reservation_id": reservationID,
+		}).Error("uc.OrderService.ReleaseStockReservation() got error")
 	}
-	return result
 }
 
-func (uc *OrderUsecase) validateProduct(ctx context.Context, items []models.CheckoutItem) error {
+// validateProduct checks the items against product service and returns the products it looked up.
+func (uc *OrderUsecase) validateProduct(ctx context.Context, items []models.CheckoutItem, quote exchangerate.Quote) (map[int64]models.Product, error) {
 	seen := map[int64]bool{}
+	productIDs := make([]int64, 0, len(items))
 	for _, item := range items {
 		// check duplicate
 		if seen[item.ProductID] {
-			return fmt.Errorf("Duplicate product: %d", item.ProductID)
+			return nil, invalidCheckout("Duplicate product: %d", item.ProductID)
 		}
 
-		// get product info at product service
-		productInfo, err := uc.OrderService.GetProductInfo(ctx, item.ProductID)
-		if err != nil {
-			return fmt.Errorf("Failed get product info: %d, err : %v", item.ProductID, err)
-		}
+		seen[item.ProductID] = true
+		productIDs = append(productIDs, item.ProductID)
+	}
+
+	// get every product info at product service at once
+	productsInfo, err := uc.OrderService.GetProductsInfo(ctx, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("Failed get product info, err : %w", err)
+	}
+
+	for _, item := range items {
+		productInfo := productsInfo[item.ProductID]
 
 		// quantity
 		if item.Quantity <= 0 || item.Quantity > 1000 {
-			return fmt.Errorf("invalid quantity product %d, maximum is 1000", item.ProductID)
+			return nil, invalidCheckout("invalid quantity product %d, maximum is 1000", item.ProductID)
 		}
 
-		// price
-		if item.Price != productInfo.Price {
-			return fmt.Errorf("Invalid price for product %d", item.ProductID)
+		// price, product service lists prices in the base currency
+		price, err := productInfo.Price.ConvertTo(quote.Rate.Inverse(), quote.Currency)
+		if err != nil || item.Price != price {
+			return nil, invalidCheckout("Invalid price for product %d", item.ProductID)
 		}
 
 		// check stock
 		if item.Quantity > productInfo.Stock {
-			return fmt.Errorf("Invalid product quantity %d, stock left %d", item.ProductID, productInfo.Stock)
+			return nil, invalidCheckout("Invalid product quantity %d, stock left %d", item.ProductID, productInfo.Stock)
+		}
+	}
+
+	return productsInfo, nil
+}
+
+// applyCoupons discounts the cart with the requested coupons, in the order they were given. Coupons
+// are only checked here; they are reserved when the order is saved.
+func (uc *OrderUsecase) applyCoupons(ctx context.Context, param *models.CheckoutRequest, orderItems []models.OrderItem, subtotal money.Amount, quote exchangerate.Quote) (models.DiscountBreakdown, error) {
+	codes := make([]string, 0, len(param.CouponCodes))
+	seen := map[string]bool{}
+	for _, code := range param.CouponCodes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" {
+			continue
+		}
+
+		if seen[code] {
+			return models.DiscountBreakdown{}, invalidCheckout("Duplicate coupon: %s", code)
+		}
+
+		seen[code] = true
+		codes = append(codes, code)
+	}
+
+	if len(codes) == 0 {
+		return models.DiscountBreakdown{
+			Subtotal: subtotal,
+			Total:    subtotal,
+		}, nil
+	}
+
+	coupons, usage, err := uc.OrderService.GetCoupons(ctx, codes, param.UserID)
+	if err != nil {
+		return models.DiscountBreakdown{}, err
+	}
+
+	couponsByCode := make(map[string]models.Coupon, len(coupons))
+	for _, coupon := range coupons {
+		couponsByCode[coupon.Code] = coupon
+	}
+
+	requested := make([]models.Coupon, len(codes))
+	for index, code := range codes {
+		coupon, isExist := couponsByCode[code]
+		if !isExist {
+			return models.DiscountBreakdown{}, invalidCheckout("Unknown coupon: %s", code)
+		}
+
+		requested[index] = coupon
+	}
+
+	return promotion.Apply(requested, orderItems, usage, quote.Rate.Inverse(), quote.Currency, time.Now())
+}
+
+// calculateTax taxes every line on what is paid for it once its share of the order discount is taken
+// off, and records the discount share and tax on the items.
+func (uc *OrderUsecase) calculateTax(ctx context.Context, param *models.CheckoutRequest, orderItems []models.OrderItem, discount money.Amount, quote exchangerate.Quote) (tax.Result, error) {
+	unit, err := money.MinorUnit(quote.Currency)
+	if err != nil {
+		return tax.Result{}, invalidCheckout("Unsupported currency %s", quote.Currency)
+	}
+
+	shares := promotion.Allocate(discount, orderItems, unit)
+
+	lines := make([]tax.Line, len(orderItems))
+	for index, item := range orderItems {
+		orderItems[index].DiscountAmount = shares[index]
+		lines[index] = tax.Line{
+			ProductID:  item.ProductID,
+			CategoryID: item.CategoryID,
+			Amount:     item.LineTotal - shares[index],
 		}
 	}
 
-	return nil
+	result, err := uc.TaxCalculator.Calculate(ctx, tax.Jurisdiction{
+		Country: param.ShippingCountry,
+		Region:  param.ShippingRegion,
+	}, quote.Currency, lines)
+	if err != nil {
+		return tax.Result{}, fmt.Errorf("Failed calculate tax, err : %w", err)
+	}
+
+	for index, line := range result.Lines {
+		orderItems[index].TaxRateBps = int64(line.Rate)
+		orderItems[index].TaxAmount = line.Amount
+	}
+
+	return result, nil
 }
 
-func (uc *OrderUsecase) calculateOrderSummary(items []models.CheckoutItem) (int, float64) {
+// QuoteShipping lists the shipping options of a cart so the client can pick one before checkout.
+func (uc *OrderUsecase) QuoteShipping(ctx context.Context, param *models.ShippingQuoteRequest) (models.ShippingQuoteResponse, error) {
+	productIDs := make([]int64, 0, len(param.Items))
+	for _, item := range param.Items {
+		if item.Qty <= 0 || item.Qty > 1000 {
+			return models.ShippingQuoteResponse{}, invalidCheckout("invalid quantity product %d, maximum is 1000", item.ProductID)
+		}
+
+		productIDs = append(productIDs, item.ProductID)
+	}
+
+	quote, err := uc.quoteCurrency(ctx, param.Currency)
+	if err != nil {
+		return models.ShippingQuoteResponse{}, err
+	}
+
+	productsInfo, err := uc.OrderService.GetProductsInfo(ctx, productIDs)
+	if err != nil {
+		return models.ShippingQuoteResponse{}, fmt.Errorf("Failed get product info, err : %w", err)
+	}
+
+	parcel := uc.parcelOf(param.Items, productsInfo)
+	options, err := uc.shippingOptions(ctx, shipping.Destination{
+		Country: param.ShippingCountry,
+		Region:  param.ShippingRegion,
+	}, parcel, quote)
+	if err != nil {
+		return models.ShippingQuoteResponse{}, err
+	}
+
+	return models.ShippingQuoteResponse{
+		Currency: quote.Currency,
+		Weight:   parcel.Weight,
+		Options:  options,
+	}, nil
+}
+
+// selectShipping prices the shipping method picked at checkout, standard when none was picked.
+func (uc *OrderUsecase) selectShipping(ctx context.Context, param *models.CheckoutRequest, productsInfo map[int64]models.Product, quote exchangerate.Quote) (models.ShippingOption, error) {
+	method := strings.ToLower(strings.TrimSpace(param.ShippingMethod))
+	if method == "" {
+		method = shipping.MethodStandard
+	}
+
+	options, err := uc.shippingOptions(ctx, shipping.Destination{
+		Country: param.ShippingCountry,
+		Region:  param.ShippingRegion,
+	}, uc.parcelOf(kafka.ProductItemsFromCheckoutItems(param.Items), productsInfo), quote)
+	if err != nil {
+		return models.ShippingOption{}, err
+	}
+
+	for _, option := range options {
+		if option.Method == method {
+			return option, nil
+		}
+	}
+
+	return models.ShippingOption{}, invalidCheckout("Shipping method %s is not available for this order", method)
+}
+
+// shippingOptions quotes the parcel and converts the fees from the base currency to the one charged.
+func (uc *OrderUsecase) shippingOptions(ctx context.Context, destination shipping.Destination, parcel shipping.Parcel, quote exchangerate.Quote) ([]models.ShippingOption, error) {
+	quoted, err := uc.ShippingProvider.Quote(ctx, destination, parcel)
+	if err != nil {
+		if errors.Is(err, shipping.ErrUnsupportedDestination) {
+			return nil, invalidCheckout("No shipping to %s", destination)
+		}
+
+		return nil, fmt.Errorf("Failed get shipping quote, err : %w", err)
+	}
+
+	options := make([]models.ShippingOption, len(quoted))
+	for index, option := range quoted {
+		fee, err := option.Fee.ConvertTo(quote.Rate.Inverse(), quote.Currency)
+		if err != nil {
+			return nil, invalidCheckout("shipping fee is too large")
+		}
+
+		options[index] = models.ShippingOption{
+			Method:  option.Method,
+			Carrier: option.Carrier,
+			Fee:     fee,
+			MinDays: option.MinDays,
+			MaxDays: option.MaxDays,
+		}
+	}
+
+	return options, nil
+}
+
+func (uc *OrderUsecase) parcelOf(items []models.ProductItem, productsInfo map[int64]models.Product) shipping.Parcel {
+	var parcel shipping.Parcel
+	for _, item := range items {
+		weight := productsInfo[item.ProductID].Weight
+		if weight <= 0 {
+			weight = uc.DefaultItemWeight
+		}
+
+		parcel.Weight += weight * int64(item.Qty)
+		parcel.Quantity += item.Qty
+	}
+
+	return parcel
+}
+
+// quoteCurrency returns the rate of the currency the order is charged in, the base currency when
+// the request does not name one.
+func (uc *OrderUsecase) quoteCurrency(ctx context.Context, currency string) (exchangerate.Quote, error) {
+	if currency == "" {
+		currency = uc.RateProvider.BaseCurrency()
+	}
+
+	quote, err := uc.RateProvider.Quote(ctx, currency)
+	if err != nil {
+		if errors.Is(err, exchangerate.ErrUnsupportedCurrency) {
+			return exchangerate.Quote{}, invalidCheckout("Unsupported currency %s", currency)
+		}
+
+		return exchangerate.Quote{}, fmt.Errorf("Failed get exchange rate, err : %w", err)
+	}
+
+	// a provider may quote a currency whose amounts cannot be kept in hundredths
+	err = money.CheckCurrency(quote.Currency)
+	if err != nil {
+		return exchangerate.Quote{}, invalidCheckout("Unsupported currency %s", currency)
+	}
+
+	return quote, nil
+}
+
+func invalidCheckout(format string, args ...any) error {
+	return &constant.CheckoutValidationError{
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// calculateOrderSummary adds up the priced lines. Line totals are exact in minor units, so the order
+// total is the exact sum of its lines and nothing is rounded; a total that does not fit is rejected.
+func (uc *OrderUsecase) calculateOrderSummary(items []models.OrderItem) (int, money.Amount, error) {
 	var totalQty int
-	var totalAmount float64
+	var totalAmount money.Amount
 
 	for _, item := range items {
+		var err error
+
 		totalQty += item.Quantity
-		totalAmount += float64(item.Quantity) * item.Price
+		totalAmount, err = totalAmount.Add(item.LineTotal)
+		if err != nil {
+			return 0, 0, invalidCheckout("order total is too large")
+		}
 	}
 
-	return totalQty, totalAmount
+	return totalQty, totalAmount, nil
 }
 
 func (uc *OrderUsecase) constructOrderDetail(items []models.CheckoutItem) (string, string) {
@@ -189,11 +657,233 @@ func (uc *OrderUsecase) constructOrderDetail(items []models.CheckoutItem) (strin
 	return string(productJSON), string(historyJSON)
 }
 
-func (uc *OrderUsecase) GetOrderHistoryByUserID(ctx context.Context, param *models.OrderHistoryParam) ([]models.OrderHistoryResponse, error) {
+// constructOrderItems snapshots name and price of every product as they are at checkout.
+func (uc *OrderUsecase) constructOrderItems(items []models.CheckoutItem, productsInfo map[int64]models.Product) ([]models.OrderItem, error) {
+	now := time.Now()
+	orderItems := make([]models.OrderItem, len(items))
+
+	for index, item := range items {
+		lineTotal, err := item.Price.Mul(int64(item.Quantity))
+		if err != nil {
+			return nil, invalidCheckout("total of product %d is too large", item.ProductID)
+		}
+
+		orderItems[index] = models.OrderItem{
+			ProductID:   item.ProductID,
+			ProductName: productsInfo[item.ProductID].Name,
+			CategoryID:  productsInfo[item.ProductID].CategoryID,
+			UnitPrice:   item.Price,
+			Quantity:    item.Quantity,
+			LineTotal:   lineTotal,
+			CreateTime:  now,
+		}
+	}
+
+	return orderItems, nil
+}
+
+func (uc *OrderUsecase) GetOrderHistoryByUserID(ctx context.Context, param *models.OrderHistoryParam) (models.OrderHistoryPage, error) {
+	if param.Cursor != "" {
+		cursor, err := decodeOrderHistoryCursor(param.Cursor)
+		if err != nil {
+			return models.OrderHistoryPage{}, err
+		}
+
+		param.After = &cursor
+	}
+
 	orderHistory, err := uc.OrderService.GetOrderHistoryByUserID(ctx, param)
+	if err != nil {
+		return models.OrderHistoryPage{}, err
+	}
+
+	page := models.OrderHistoryPage{
+		Orders: orderHistory,
+	}
+
+	// the repository reads one extra row to tell whether another page exists
+	if len(orderHistory) > param.Limit {
+		page.Orders = orderHistory[:param.Limit]
+		page.HasMore = true
+
+		last := page.Orders[len(page.Orders)-1]
+		page.NextCursor = encodeOrderHistoryCursor(models.OrderHistoryCursor{
+			CreateTime: last.CreateTime,
+			ID:         last.OrderID,
+		})
+	}
+
+	if page.Orders == nil {
+		page.Orders = []models.OrderHistoryResponse{}
+	}
+
+	return page, nil
+}
+
+func encodeOrderHistoryCursor(cursor models.OrderHistoryCursor) string {
+	cursorJSON, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(cursorJSON)
+}
+
+func decodeOrderHistoryCursor(value string) (models.OrderHistoryCursor, error) {
+	var cursor models.OrderHistoryCursor
+
+	cursorJSON, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return models.OrderHistoryCursor{}, constant.ErrInvalidCursor
+	}
+
+	err = json.Unmarshal(cursorJSON, &cursor)
+	if err != nil || cursor.ID <= 0 {
+		return models.OrderHistoryCursor{}, constant.ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// GetOrderByID returns a single order owned by the user. Orders owned by someone else are reported as
+// not found so their existence is not leaked.
+func (uc *OrderUsecase) GetOrderByID(ctx context.Context, param *models.OrderInfoParam) (models.OrderHistoryResponse, error) {
+	order, err := uc.OrderService.GetOrderInfoByOrderID(ctx, param.OrderID)
+	if err != nil {
+		return models.OrderHistoryResponse{}, err
+	}
+
+	if order.UserID != param.UserID {
+		return models.OrderHistoryResponse{}, constant.ErrOrderNotFound
+	}
+
+	orderDetail, err := uc.OrderService.GetOrderDetailByID(ctx, order.OrderDetailID)
+	if err != nil {
+		return models.OrderHistoryResponse{}, err
+	}
+
+	products, err := uc.OrderService.GetOrderProducts(ctx, order.ID, orderDetail)
+	if err != nil {
+		return models.OrderHistoryResponse{}, err
+	}
+
+	orderHistory, err := uc.OrderService.GetOrderStatusHistory(ctx, order.ID, orderDetail)
+	if err != nil {
+		return models.OrderHistoryResponse{}, err
+	}
+
+	discounts, err := uc.OrderService.GetOrderDiscounts(ctx, order.ID)
+	if err != nil {
+		return models.OrderHistoryResponse{}, err
+	}
+
+	return models.OrderHistoryResponse{
+		OrderID:               order.ID,
+		Subtotal:              order.Subtotal,
+		DiscountAmount:        order.DiscountAmount,
+		Discounts:             discounts,
+		TaxAmount:             order.TaxAmount,
+		TaxMode:               order.TaxMode,
+		ShippingMethod:        order.ShippingMethod,
+		ShippingFee:           order.ShippingFee,
+		TotalAmount:           order.Amount,
+		Currency:              order.Currency,
+		TotalQty:              order.TotalQty,
+		Status:                constant.OrderStatusTranslated[order.Status],
+		PaymentMethod:         order.PaymentMethod,
+		ShippingAddress:       order.ShippingAddress,
+		ShippingAddressDetail: order.ShippingAddressDetail,
+		Products:              products,
+		History:               orderHistory,
+		CreateTime:            order.CreateTime,
+	}, nil
+}
+
+// CancelOrder cancels an order on behalf of its owner, returning the reserved stock to the product
+// service and notifying payment service so the charge can be voided.
+func (uc *OrderUsecase) CancelOrder(ctx context.Context, param *models.CancelOrderParam) error {
+	order, err := uc.OrderService.GetOrderInfoByOrderID(ctx, param.OrderID)
+	if err != nil {
+		return err
+	}
+
+	if order.UserID != param.UserID {
+		return constant.ErrOrderNotFound
+	}
+
+	if !constant.CanTransitionOrderStatus(order.Status, constant.OrderStatusCancelled) {
+		return &constant.InvalidStatusTransitionError{
+			OrderID: order.ID,
+			From:    order.Status,
+			To:      constant.OrderStatusCancelled,
+		}
+	}
+
+	reason := param.Reason
+	if reason == "" {
+		reason = "cancelled by customer"
+	}
+
+	updateParam := models.UpdateOrderStatusParam{
+		OrderID:   order.ID,
+		Status:    constant.OrderStatusCancelled,
+		Reason:    reason,
+		ActorType: constant.ActorTypeUser,
+		ActorID:   strconv.FormatInt(param.UserID, 10),
+	}
+
+	return uc.OrderService.TransitionOrder(ctx, &updateParam, func(order models.Order, orderDetail models.OrderDetail) ([]models.OutboxMessage, error) {
+		stockReturn, err := kafka.NewStockReturnMessage(ctx, order, orderDetail, reason)
+		if err != nil {
+			return nil, err
+		}
+
+		orderCancelled, err := kafka.NewOutboxMessage(ctx, constant.TopicOrderCancelled, order.ID, models.OrderCancelledEvent{
+			OrderID:     order.ID,
+			UserID:      order.UserID,
+			TotalAmount: order.Amount,
+			Currency:    order.Currency,
+			Reason:      reason,
+			CancelTime:  time.Now(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return []models.OutboxMessage{stockReturn, orderCancelled}, nil
+	})
+}
+
+// ExpireUnpaidOrders expires orders that were not paid within the payment window, returning their stock
+// and announcing the expiry so payment service stops waiting for them. An order that cannot be expired
+// is logged and skipped, the next run tries it again.
+func (uc *OrderUsecase) ExpireUnpaidOrders(ctx context.Context, param *models.ExpireOrdersParam) ([]int64, error) {
+	expired, failed, err := uc.OrderService.ExpireOrders(ctx, param, func(order models.Order, orderDetail models.OrderDetail) ([]models.OutboxMessage, error) {
+		stockReturn, err := kafka.NewStockReturnMessage(ctx, order, orderDetail, "payment window elapsed")
+		if err != nil {
+			return nil, err
+		}
+
+		orderExpired, err := kafka.NewOutboxMessage(ctx, constant.TopicOrderExpired, order.ID, models.OrderExpiredEvent{
+			OrderID:     order.ID,
+			UserID:      order.UserID,
+			TotalAmount: order.Amount,
+			Currency:    order.Currency,
+			ExpireTime:  time.Now(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return []models.OutboxMessage{stockReturn, orderExpired}, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return orderHistory, nil
+	for orderID, err := range failed {
+		log.Logger.WithFields(logrus.Fields{
+			"err":      err.Error(),
+			"order_id": orderID,
+		}).Error("uc.OrderService.ExpireOrders() could not expire order")
+	}
+
+	return expired, nil
 }
@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"context"
+	"order/cmd/order/usecase"
+	"order/config"
+	"order/infrastructure/log"
+	"order/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultOrderPaymentWindow   = 30 * time.Minute
+	defaultOrderExpiryInterval  = time.Minute
+	defaultOrderExpiryBatchSize = 100
+)
+
+// OrderExpiryScheduler periodically expires orders that were not paid within the payment window.
+// It is safe to run on every replica, locked orders are skipped by the others.
+type OrderExpiryScheduler struct {
+	OrderUsecase  *usecase.OrderUsecase
+	PaymentWindow time.Duration
+	Interval      time.Duration
+	BatchSize     int
+}
+
+func NewOrderExpiryScheduler(orderUsecase *usecase.OrderUsecase, cfg config.OrderExpiryConfig) *OrderExpiryScheduler {
+	scheduler := &OrderExpiryScheduler{
+		OrderUsecase:  orderUsecase,
+		PaymentWindow: cfg.PaymentWindow,
+		Interval:      cfg.Interval,
+		BatchSize:     cfg.BatchSize,
+	}
+
+	if scheduler.PaymentWindow <= 0 {
+		scheduler.PaymentWindow = defaultOrderPaymentWindow
+	}
+
+	if scheduler.Interval <= 0 {
+		scheduler.Interval = defaultOrderExpiryInterval
+	}
+
+	if scheduler.BatchSize <= 0 {
+		scheduler.BatchSize = defaultOrderExpiryBatchSize
+	}
+
+	return scheduler
+}
+
+func (w *OrderExpiryScheduler) Start(ctx context.Context) {
+	log.Logger.Printf("[EXPIRY] Scheduler started, payment window %s", w.PaymentWindow)
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Logger.Println("[EXPIRY] Scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			param := models.ExpireOrdersParam{
+				CreatedBefore: time.Now().Add(-w.PaymentWindow),
+				Limit:         w.BatchSize,
+			}
+
+			orderIDs, err := w.OrderUsecase.ExpireUnpaidOrders(ctx, &param)
+			if err != nil {
+				log.Logger.WithFields(logrus.Fields{
+					"err": err.Error(),
+				}).Error("[EXPIRY] w.OrderUsecase.ExpireUnpaidOrders() got error")
+
+				break
+			}
+
+			if len(orderIDs) > 0 {
+				log.Logger.WithFields(logrus.Fields{
+					"order_ids": orderIDs,
+				}).Info("[EXPIRY] Expired unpaid orders")
+			}
+
+			if len(orderIDs) < w.BatchSize {
+				break
+			}
+		}
+	}
+}
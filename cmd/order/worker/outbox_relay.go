@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"context"
+	"order/cmd/order/service"
+	"order/config"
+	"order/infrastructure/log"
+	"order/kafka"
+	"order/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultOutboxPollInterval    = time.Second
+	defaultOutboxBatchSize       = 100
+	defaultOutboxRetryBackoff    = time.Second
+	defaultOutboxMaxRetryBackoff = 5 * time.Minute
+	defaultOutboxClaimTTL        = 30 * time.Second
+	defaultOutboxMaxAttempts     = 20
+)
+
+// OutboxRelay periodically drains the order outbox into kafka.
+type OutboxRelay struct {
+	OrderService  *service.OrderService
+	KafkaProducer *kafka.KafkaProducer
+	PollInterval  time.Duration
+	DrainParam    models.OutboxDrainParam
+}
+
+func NewOutboxRelay(orderService *service.OrderService, kafkaProducer *kafka.KafkaProducer, cfg config.OutboxConfig) *OutboxRelay {
+	relay := &OutboxRelay{
+		OrderService:  orderService,
+		KafkaProducer: kafkaProducer,
+		PollInterval:  cfg.PollInterval,
+		DrainParam: models.OutboxDrainParam{
+			BatchSize:       cfg.BatchSize,
+			RetryBackoff:    cfg.RetryBackoff,
+			MaxRetryBackoff: cfg.MaxRetryBackoff,
+			ClaimTTL:        cfg.ClaimTTL,
+			MaxAttempts:     cfg.MaxAttempts,
+		},
+	}
+
+	if relay.PollInterval <= 0 {
+		relay.PollInterval = defaultOutboxPollInterval
+	}
+
+	if relay.DrainParam.BatchSize <= 0 {
+		relay.DrainParam.BatchSize = defaultOutboxBatchSize
+	}
+
+	if relay.DrainParam.RetryBackoff <= 0 {
+		relay.DrainParam.RetryBackoff = defaultOutboxRetryBackoff
+	}
+
+	if relay.DrainParam.MaxRetryBackoff <= 0 {
+		relay.DrainParam.MaxRetryBackoff = defaultOutboxMaxRetryBackoff
+	}
+
+	if relay.DrainParam.ClaimTTL <= 0 {
+		relay.DrainParam.ClaimTTL = defaultOutboxClaimTTL
+	}
+
+	if relay.DrainParam.MaxAttempts <= 0 {
+		relay.DrainParam.MaxAttempts = defaultOutboxMaxAttempts
+	}
+
+	return relay
+}
+
+func (w *OutboxRelay) Start(ctx context.Context) {
+	log.Logger.Println("[OUTBOX] Relay started")
+
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Logger.Println("[OUTBOX] Relay stopped")
+			return
+		case <-ticker.C:
+		}
+
+		// keep draining while full batches come back, so a backlog clears faster than one batch per tick
+		for {
+			published, parked, err := w.OrderService.DrainOutbox(ctx, &w.DrainParam, w.KafkaProducer.PublishOutboxMessages)
+			if err != nil {
+				log.Logger.WithFields(logrus.Fields{
+					"err": err.Error(),
+				}).Error("[OUTBOX] w.OrderService.DrainOutbox() got error")
+
+				break
+			}
+
+			for _, message := range parked {
+				log.Logger.WithFields(logrus.Fields{
+					"err":         message.LastError,
+					"outbox_id":   message.ID,
+					"topic":       message.Topic,
+					"message_key": message.MessageKey,
+					"attempts":    message.Attempts,
+				}).Error("[OUTBOX] Parked message after too many failed attempts, its key is blocked")
+			}
+
+			if published < w.DrainParam.BatchSize {
+				break
+			}
+		}
+	}
+}
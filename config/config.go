@@ -36,7 +36,35 @@ func LoadConfig() Config {
 	}
 
 	if err := viper.Unmarshal(&cfg.Kafka); err != nil {
-		log.Fatalf("error unmarshal product config: %s", err)
+		log.Fatalf("error unmarshal kafka config: %s", err)
+	}
+
+	if err := viper.Unmarshal(&cfg.Outbox); err != nil {
+		log.Fatalf("error unmarshal outbox config: %s", err)
+	}
+
+	if err := viper.Unmarshal(&cfg.Expiry); err != nil {
+		log.Fatalf("error unmarshal order expiry config: %s", err)
+	}
+
+	if err := viper.Unmarshal(&cfg.Idempotency); err != nil {
+		log.Fatalf("error unmarshal idempotency config: %s", err)
+	}
+
+	if err := viper.Unmarshal(&cfg.Currency); err != nil {
+		log.Fatalf("error unmarshal currency config: %s", err)
+	}
+
+	if err := viper.Unmarshal(&cfg.Tax); err != nil {
+		log.Fatalf("error unmarshal tax config: %s", err)
+	}
+
+	if err := viper.Unmarshal(&cfg.Shipping); err != nil {
+		log.Fatalf("error unmarshal shipping config: %s", err)
+	}
+
+	if err := viper.Unmarshal(&cfg.Cart); err != nil {
+		log.Fatalf("error unmarshal cart config: %s", err)
 	}
 
 	return cfg
@@ -1,25 +1,111 @@
 package config
 
+import "time"
+
 type Config struct {
-	App      AppConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	Jwt      JwtConfig
-	Product  ProductConfig
-	Kafka    KafkaConfig
+	App         AppConfig
+	Database    DatabaseConfig
+	Redis       RedisConfig
+	Jwt         JwtConfig
+	Product     ProductConfig
+	Kafka       KafkaConfig
+	Outbox      OutboxConfig
+	Expiry      OrderExpiryConfig
+	Idempotency IdempotencyConfig
+	Currency    CurrencyConfig
+	Tax         TaxConfig
+	Shipping    ShippingConfig
+	Cart        CartConfig
 }
 
 type AppConfig struct {
-	Port string `mapstructure:"APP_PORT"`
+	Port            string        `mapstructure:"APP_PORT"`
+	ShutdownTimeout time.Duration `mapstructure:"APP_SHUTDOWN_TIMEOUT"`
 }
 
 type ProductConfig struct {
-	Host string `mapstructure:"PRODUCT_HOST"`
+	Host           string        `mapstructure:"PRODUCT_HOST"`
+	Timeout        time.Duration `mapstructure:"PRODUCT_TIMEOUT"`
+	MaxConcurrency int           `mapstructure:"PRODUCT_MAX_CONCURRENCY"`
+	BatchEnabled   bool          `mapstructure:"PRODUCT_BATCH_ENABLED"`
+	CacheStore     string        `mapstructure:"PRODUCT_CACHE_STORE"`
+	CacheTTL       time.Duration `mapstructure:"PRODUCT_CACHE_TTL"`
+
+	MaxRetries      int           `mapstructure:"PRODUCT_MAX_RETRIES"`
+	RetryBackoff    time.Duration `mapstructure:"PRODUCT_RETRY_BACKOFF"`
+	MaxRetryBackoff time.Duration `mapstructure:"PRODUCT_MAX_RETRY_BACKOFF"`
+
+	BreakerFailureRate float64       `mapstructure:"PRODUCT_BREAKER_FAILURE_RATE"`
+	BreakerMinRequests int           `mapstructure:"PRODUCT_BREAKER_MIN_REQUESTS"`
+	BreakerWindow      time.Duration `mapstructure:"PRODUCT_BREAKER_WINDOW"`
+	BreakerOpenTimeout time.Duration `mapstructure:"PRODUCT_BREAKER_OPEN_TIMEOUT"`
+
+	// ReservationEnabled holds stock at checkout, ReservationTTL should outlive ORDER_PAYMENT_WINDOW so an
+	// unpaid order is expired before product service lets go of its stock.
+	ReservationEnabled bool          `mapstructure:"PRODUCT_RESERVATION_ENABLED"`
+	ReservationTTL     time.Duration `mapstructure:"PRODUCT_RESERVATION_TTL"`
 }
 
 type KafkaConfig struct {
 	Host string `mapstructure:"KAFKA_HOST"`
 	Port string `mapstructure:"KAFKA_PORT"`
+
+	// TopicSerializers is a "topic:json|protobuf" comma list, unlisted topics are published as JSON.
+	TopicSerializers string `mapstructure:"KAFKA_TOPIC_SERIALIZERS"`
+
+	ConsumerConcurrency     int           `mapstructure:"KAFKA_CONSUMER_CONCURRENCY"`
+	ConsumerMaxAttempts     int           `mapstructure:"KAFKA_CONSUMER_MAX_ATTEMPTS"`
+	ConsumerRetryBackoff    time.Duration `mapstructure:"KAFKA_CONSUMER_RETRY_BACKOFF"`
+	ConsumerMaxRetryBackoff time.Duration `mapstructure:"KAFKA_CONSUMER_MAX_RETRY_BACKOFF"`
+}
+
+type OutboxConfig struct {
+	PollInterval    time.Duration `mapstructure:"OUTBOX_POLL_INTERVAL"`
+	BatchSize       int           `mapstructure:"OUTBOX_BATCH_SIZE"`
+	RetryBackoff    time.Duration `mapstructure:"OUTBOX_RETRY_BACKOFF"`
+	MaxRetryBackoff time.Duration `mapstructure:"OUTBOX_MAX_RETRY_BACKOFF"`
+	ClaimTTL        time.Duration `mapstructure:"OUTBOX_CLAIM_TTL"`
+	MaxAttempts     int           `mapstructure:"OUTBOX_MAX_ATTEMPTS"`
+}
+
+type OrderExpiryConfig struct {
+	PaymentWindow time.Duration `mapstructure:"ORDER_PAYMENT_WINDOW"`
+	Interval      time.Duration `mapstructure:"ORDER_EXPIRY_INTERVAL"`
+	BatchSize     int           `mapstructure:"ORDER_EXPIRY_BATCH_SIZE"`
+}
+
+type IdempotencyConfig struct {
+	Store   string        `mapstructure:"IDEMPOTENCY_STORE"`
+	TTL     time.Duration `mapstructure:"IDEMPOTENCY_TTL"`
+	LockTTL time.Duration `mapstructure:"IDEMPOTENCY_LOCK_TTL"`
+}
+
+type CurrencyConfig struct {
+	BaseCurrency string `mapstructure:"CURRENCY_BASE"`
+	RateProvider string `mapstructure:"CURRENCY_RATE_PROVIDER"`
+	// Rates is a "currency:rate" comma list used by the static provider.
+	Rates     string `mapstructure:"CURRENCY_RATES"`
+	RatesFile string `mapstructure:"CURRENCY_RATES_FILE"`
+}
+
+type TaxConfig struct {
+	// Calculator is none or table, table reads the rates of every jurisdiction from TableFile.
+	Calculator string `mapstructure:"TAX_CALCULATOR"`
+	TableFile  string `mapstructure:"TAX_TABLE_FILE"`
+}
+
+type ShippingConfig struct {
+	// Provider is none or table, table reads the rates of every zone from RatesFile.
+	Provider  string `mapstructure:"SHIPPING_PROVIDER"`
+	RatesFile string `mapstructure:"SHIPPING_RATES_FILE"`
+	// DefaultItemWeight in grams is used for products without a weight.
+	DefaultItemWeight int64 `mapstructure:"SHIPPING_DEFAULT_ITEM_WEIGHT"`
+}
+
+type CartConfig struct {
+	// Store is redis, which caches carts in front of postgres, or postgres alone.
+	Store    string        `mapstructure:"CART_STORE"`
+	CacheTTL time.Duration `mapstructure:"CART_CACHE_TTL"`
 }
 
 type DatabaseConfig struct {
@@ -29,6 +115,8 @@ type DatabaseConfig struct {
 	Password string `mapstructure:"DB_PASSWORD"`
 	Name     string `mapstructure:"DB_NAME"`
 	Port     string `mapstructure:"DB_PORT"`
+
+	MigrateOnStartup bool `mapstructure:"DB_MIGRATE_ON_STARTUP"`
 }
 
 type RedisConfig struct {
@@ -1,5 +0,0 @@
-CREATE TABLE order_request_log (
-    id BiGSERIAL PRIMARY KEY,
-    idempotency_token text unique not null,
-    create_time timestamp default current_timestamp
-)
\ No newline at end of file
@@ -1,5 +1,5 @@
-CREATE TABLE order_detail (
+CREATE TABLE IF NOT EXISTS order_detail (
     id BiGSERIAL PRIMARY KEY,
     products text not null,
     order_history text not null
-)
\ No newline at end of file
+);
@@ -1,4 +1,4 @@
-CREATE TABLE orders (
+CREATE TABLE IF NOT EXISTS orders (
     id BiGSERIAL PRIMARY KEY,
     user_id bigint not null,
     amount numeric not null,
@@ -9,4 +9,6 @@ CREATE TABLE orders (
     order_detail_id bigint references order_detail(id),
     create_time timestamp default current_timestamp,
     update_time timestamp default current_timestamp
-)
\ No newline at end of file
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_user_create_time ON orders (user_id, create_time, id);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS order_request_log (
+    id BiGSERIAL PRIMARY KEY,
+    idempotency_key text unique not null,
+    status varchar(20) not null,
+    order_id bigint,
+    create_time timestamp default current_timestamp,
+    expire_time timestamp not null
+);
+
+-- databases created from the old hand-applied file have idempotency_token and none of the
+-- columns the idempotency store needs.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'order_request_log' AND column_name = 'idempotency_token'
+    ) THEN
+        ALTER TABLE order_request_log RENAME COLUMN idempotency_token TO idempotency_key;
+    END IF;
+END $$;
+
+ALTER TABLE order_request_log ADD COLUMN IF NOT EXISTS status varchar(20) not null default 'completed';
+ALTER TABLE order_request_log ADD COLUMN IF NOT EXISTS order_id bigint;
+ALTER TABLE order_request_log ADD COLUMN IF NOT EXISTS expire_time timestamp not null default current_timestamp;
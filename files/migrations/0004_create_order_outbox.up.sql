@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS order_outbox (
+    id BiGSERIAL PRIMARY KEY,
+    topic varchar(100) not null,
+    message_key varchar(100) not null,
+    payload text not null,
+    attempts integer not null default 0,
+    last_error text,
+    next_attempt_time timestamp default current_timestamp,
+    published_time timestamp,
+    create_time timestamp default current_timestamp
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_outbox_pending ON order_outbox (id) WHERE published_time IS NULL;
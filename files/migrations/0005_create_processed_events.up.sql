@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS processed_events (
+    consumer varchar(100) not null,
+    event_key varchar(255) not null,
+    processed_time timestamp default current_timestamp,
+    PRIMARY KEY (consumer, event_key)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS order_items (
+    id BiGSERIAL PRIMARY KEY,
+    order_id bigint not null references orders(id),
+    product_id bigint not null,
+    product_name varchar(255) not null default '',
+    unit_price numeric not null,
+    quantity integer not null,
+    line_total numeric not null,
+    create_time timestamp default current_timestamp
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items (order_id);
+CREATE INDEX IF NOT EXISTS idx_order_items_product_id ON order_items (product_id);
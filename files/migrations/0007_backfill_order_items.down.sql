@@ -0,0 +1 @@
+-- nothing to undo, the backfilled rows are dropped together with order_items.
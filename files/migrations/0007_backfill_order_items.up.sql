@@ -0,0 +1,18 @@
+-- copy order_detail.products of orders placed before order_items existed.
+-- product names were never stored, so the snapshot of these rows is left empty.
+-- safe to run more than once, orders that already have items are skipped.
+INSERT INTO order_items (order_id, product_id, product_name, unit_price, quantity, line_total, create_time)
+SELECT
+    o.id,
+    (item ->> 'product_id')::bigint,
+    '',
+    (item ->> 'price')::numeric,
+    (item ->> 'quantity')::integer,
+    (item ->> 'price')::numeric * (item ->> 'quantity')::integer,
+    o.create_time
+FROM orders o
+JOIN order_detail od ON od.id = o.order_detail_id
+CROSS JOIN LATERAL jsonb_array_elements(od.products::jsonb) AS item
+WHERE NOT EXISTS (
+    SELECT 1 FROM order_items oi WHERE oi.order_id = o.id
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id BiGSERIAL PRIMARY KEY,
+    order_id bigint not null references orders(id),
+    from_status integer,
+    to_status integer not null,
+    actor_type varchar(20) not null,
+    actor_id varchar(100) not null default '',
+    reason text not null default '',
+    source_event_id varchar(255) not null default '',
+    create_time timestamp default current_timestamp
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order_id ON order_status_history (order_id, id);
@@ -0,0 +1,28 @@
+-- copy order_detail.order_history of orders placed before order_status_history existed.
+-- orders that already have rows are skipped.
+INSERT INTO order_status_history (order_id, from_status, to_status, actor_type, actor_id, reason, create_time)
+SELECT
+    o.id,
+    LAG(status.to_status) OVER (PARTITION BY o.id ORDER BY entry.ordinality),
+    status.to_status,
+    'system',
+    'backfill',
+    COALESCE(entry.value ->> 'reason', ''),
+    (entry.value ->> 'timestamp')::timestamptz
+FROM orders o
+JOIN order_detail od ON od.id = o.order_detail_id
+CROSS JOIN LATERAL jsonb_array_elements(od.order_history::jsonb) WITH ORDINALITY AS entry(value, ordinality)
+CROSS JOIN LATERAL (
+    SELECT CASE lower(entry.value ->> 'status')
+        WHEN 'created' THEN 0
+        WHEN 'processing' THEN 1
+        WHEN 'completed' THEN 2
+        WHEN 'cancelled' THEN 3
+        WHEN 'failed' THEN 4
+        WHEN 'expired' THEN 5
+    END AS to_status
+) status
+WHERE status.to_status IS NOT NULL
+AND NOT EXISTS (
+    SELECT 1 FROM order_status_history osh WHERE osh.order_id = o.id
+);
@@ -0,0 +1,4 @@
+ALTER TABLE order_items ALTER COLUMN line_total TYPE numeric;
+ALTER TABLE order_items ALTER COLUMN unit_price TYPE numeric;
+ALTER TABLE orders ALTER COLUMN amount TYPE numeric;
+ALTER TABLE orders DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,5 @@
+-- amounts are kept in hundredths of the order currency.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS currency varchar(3) not null default 'IDR';
+ALTER TABLE orders ALTER COLUMN amount TYPE numeric(20,2);
+ALTER TABLE order_items ALTER COLUMN unit_price TYPE numeric(20,2);
+ALTER TABLE order_items ALTER COLUMN line_total TYPE numeric(20,2);
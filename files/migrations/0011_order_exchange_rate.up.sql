@@ -0,0 +1,9 @@
+-- orders placed so far were charged in the base currency.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS base_currency varchar(3) not null default 'IDR';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS base_amount numeric(20,2);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS exchange_rate numeric(30,10) not null default 1;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS exchange_rate_time timestamp;
+
+UPDATE orders SET base_amount = amount, exchange_rate_time = create_time WHERE base_amount IS NULL;
+
+ALTER TABLE orders ALTER COLUMN base_amount SET NOT NULL;
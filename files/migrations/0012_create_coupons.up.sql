@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS coupons (
+    id BiGSERIAL PRIMARY KEY,
+    code varchar(50) unique not null,
+    type varchar(20) not null,
+    discount_value numeric(20,2) not null default 0,
+    buy_product_id bigint not null default 0,
+    buy_qty integer not null default 0,
+    get_qty integer not null default 0,
+    min_spend numeric(20,2) not null default 0,
+    max_uses integer not null default 0,
+    max_uses_per_user integer not null default 0,
+    used_count integer not null default 0,
+    start_time timestamp,
+    expire_time timestamp,
+    create_time timestamp default current_timestamp
+);
+
+CREATE TABLE IF NOT EXISTS coupon_redemptions (
+    id BiGSERIAL PRIMARY KEY,
+    coupon_id bigint not null references coupons(id),
+    code varchar(50) not null,
+    user_id bigint not null,
+    order_id bigint not null references orders(id),
+    discount_amount numeric(20,2) not null,
+    create_time timestamp default current_timestamp,
+    UNIQUE (coupon_id, order_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_user ON coupon_redemptions (coupon_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_order ON coupon_redemptions (order_id);
+
+-- subtotal is the price before coupons, amount stays the total charged.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS subtotal numeric(20,2);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_amount numeric(20,2) not null default 0;
+
+UPDATE orders SET subtotal = amount WHERE subtotal IS NULL;
+
+ALTER TABLE orders ALTER COLUMN subtotal SET NOT NULL;
@@ -0,0 +1,9 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_region;
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_country;
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_mode;
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_amount;
+
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_amount;
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_rate;
+ALTER TABLE order_items DROP COLUMN IF EXISTS discount_amount;
+ALTER TABLE order_items DROP COLUMN IF EXISTS category_id;
@@ -0,0 +1,11 @@
+-- tax_rate is a percentage, 11.00 is 11%. discount_amount is the line's share of the order discount.
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS category_id integer not null default 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS discount_amount numeric(20,2) not null default 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_rate numeric(5,2) not null default 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_amount numeric(20,2) not null default 0;
+
+-- orders placed so far were not taxed.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_amount numeric(20,2) not null default 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_mode varchar(10) not null default 'exclusive';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_country varchar(2) not null default '';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_region varchar(10) not null default '';
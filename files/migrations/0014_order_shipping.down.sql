@@ -0,0 +1,3 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_fee;
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_carrier;
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_method;
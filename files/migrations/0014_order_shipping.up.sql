@@ -0,0 +1,4 @@
+-- orders placed so far were shipped standard without a fee.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_method varchar(20) not null default 'standard';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_carrier varchar(50) not null default '';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_fee numeric(20,2) not null default 0;
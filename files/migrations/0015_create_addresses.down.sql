@@ -0,0 +1,3 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_address_detail;
+
+DROP TABLE IF EXISTS addresses;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS addresses (
+    id BiGSERIAL PRIMARY KEY,
+    user_id bigint not null,
+    label varchar(50) not null default '',
+    recipient varchar(100) not null,
+    phone varchar(20) not null,
+    line1 varchar(255) not null,
+    line2 varchar(255) not null default '',
+    city varchar(100) not null,
+    region varchar(3) not null default '',
+    postal_code varchar(10) not null default '',
+    country varchar(2) not null,
+    is_default boolean not null default false,
+    create_time timestamp default current_timestamp,
+    update_time timestamp default current_timestamp
+);
+
+CREATE INDEX IF NOT EXISTS idx_addresses_user_id ON addresses (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_addresses_user_default ON addresses (user_id) WHERE is_default;
+
+-- a copy of the saved address an order ships to, orders with a free-text address keep it NULL.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_address_detail jsonb;
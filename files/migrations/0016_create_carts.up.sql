@@ -0,0 +1,10 @@
+-- user_id is 0 for a guest cart, a signed-in user has at most one cart.
+CREATE TABLE IF NOT EXISTS carts (
+    id varchar(36) PRIMARY KEY,
+    user_id bigint not null default 0,
+    items jsonb not null default '[]',
+    create_time timestamp default current_timestamp,
+    update_time timestamp default current_timestamp
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_carts_user_id ON carts (user_id) WHERE user_id <> 0;
@@ -0,0 +1,2 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS stock_reservation_expire_time;
+ALTER TABLE orders DROP COLUMN IF EXISTS stock_reservation_id;
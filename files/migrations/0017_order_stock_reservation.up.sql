@@ -0,0 +1,3 @@
+-- orders placed so far sent stock.update instead of reserving stock, they have no reservation.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS stock_reservation_id varchar(64) not null default '';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS stock_reservation_expire_time timestamp;
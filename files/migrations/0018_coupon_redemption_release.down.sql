@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_coupon_redemptions_user;
+CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_user ON coupon_redemptions (coupon_id, user_id);
+
+-- released redemptions did not exist before, drop them as releasing used to.
+DELETE FROM coupon_redemptions WHERE released_time IS NOT NULL;
+ALTER TABLE coupon_redemptions DROP COLUMN IF EXISTS released_time;
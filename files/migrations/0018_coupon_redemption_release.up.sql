@@ -0,0 +1,5 @@
+-- a released redemption is kept for the order's discount breakdown but no longer counts against limits.
+ALTER TABLE coupon_redemptions ADD COLUMN IF NOT EXISTS released_time timestamp;
+
+DROP INDEX IF EXISTS idx_coupon_redemptions_user;
+CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_user ON coupon_redemptions (coupon_id, user_id) WHERE released_time IS NULL;
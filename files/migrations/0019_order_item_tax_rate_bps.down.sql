@@ -0,0 +1,5 @@
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_rate numeric(5,2) not null default 0;
+
+UPDATE order_items SET tax_rate = tax_rate_bps / 100.0 WHERE tax_rate_bps <> 0;
+
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_rate_bps;
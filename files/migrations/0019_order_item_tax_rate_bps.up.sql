@@ -0,0 +1,6 @@
+-- tax_rate_bps is in basis points, 1100 is 11%.
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_rate_bps integer not null default 0;
+
+UPDATE order_items SET tax_rate_bps = round(tax_rate * 100) WHERE tax_rate <> 0;
+
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_rate;
@@ -0,0 +1,2 @@
+-- version is bumped on every save, a save of a cart read at an older version is rejected.
+ALTER TABLE carts ADD COLUMN IF NOT EXISTS version bigint not null default 0;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_order_outbox_key_pending;
+DROP INDEX IF EXISTS idx_order_outbox_pending;
+CREATE INDEX IF NOT EXISTS idx_order_outbox_pending ON order_outbox (id) WHERE published_time IS NULL;
+
+ALTER TABLE order_outbox DROP COLUMN IF EXISTS failed_time;
@@ -0,0 +1,7 @@
+-- a message that failed too many times is parked with failed_time set, it blocks the later messages of
+-- its key until someone republishes or drops it.
+ALTER TABLE order_outbox ADD COLUMN IF NOT EXISTS failed_time timestamp;
+
+DROP INDEX IF EXISTS idx_order_outbox_pending;
+CREATE INDEX IF NOT EXISTS idx_order_outbox_pending ON order_outbox (next_attempt_time, id) WHERE published_time IS NULL AND failed_time IS NULL;
+CREATE INDEX IF NOT EXISTS idx_order_outbox_key_pending ON order_outbox (message_key, id) WHERE published_time IS NULL;
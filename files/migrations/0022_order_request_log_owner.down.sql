@@ -0,0 +1 @@
+ALTER TABLE order_request_log DROP COLUMN IF EXISTS owner_token;
@@ -0,0 +1,3 @@
+-- owner_token is the random token handed to the request that reserved the key, only that request
+-- may complete or release it.
+ALTER TABLE order_request_log ADD COLUMN IF NOT EXISTS owner_token text;
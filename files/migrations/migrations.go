@@ -0,0 +1,8 @@
+// Package migrations holds the versioned schema of the order service. Files are named
+// <version>_<name>.up.sql and <version>_<name>.down.sql and are applied in version order.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var Files embed.FS
@@ -0,0 +1,182 @@
+package address
+
+import (
+	"order/infrastructure/constant"
+	"order/models"
+	"regexp"
+	"strings"
+)
+
+// rule is what a country requires of its addresses.
+type rule struct {
+	postalCode    *regexp.Regexp
+	requireRegion bool
+	regions       map[string]bool
+}
+
+var (
+	countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+	regionCode  = regexp.MustCompile(`^[A-Z0-9]{1,3}$`)
+	phoneNumber = regexp.MustCompile(`^\+?[0-9][0-9 -]{5,19}$`)
+
+	// countries without a rule only need a postal code of at most 10 characters, when they use one
+	anyPostalCode = regexp.MustCompile(`^[A-Z0-9 -]{0,10}$`)
+)
+
+var rules = map[string]rule{
+	"ID": {
+		postalCode:    regexp.MustCompile(`^\d{5}$`),
+		requireRegion: true,
+		regions: setOf("AC", "BA", "BB", "BE", "BT", "GO", "JA", "JB", "JI", "JK", "JT", "KB", "KI", "KR", "KS", "KT", "KU",
+			"LA", "MA", "MU", "NB", "NT", "PA", "PB", "PD", "PE", "PS", "PT", "RI", "SA", "SB", "SG", "SN", "SR", "SS", "ST", "SU", "YO"),
+	},
+	"SG": {
+		postalCode: regexp.MustCompile(`^\d{6}$`),
+	},
+	"MY": {
+		postalCode:    regexp.MustCompile(`^\d{5}$`),
+		requireRegion: true,
+		regions:       setOf("01", "02", "03", "04", "05", "06", "07", "08", "09", "10", "11", "12", "13", "14", "15", "16"),
+	},
+	"US": {
+		postalCode:    regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+		requireRegion: true,
+		regions: setOf("AL", "AK", "AZ", "AR", "CA", "CO", "CT", "DE", "DC", "FL", "GA", "HI", "ID", "IL", "IN", "IA", "KS", "KY",
+			"LA", "ME", "MD", "MA", "MI", "MN", "MS", "MO", "MT", "NE", "NV", "NH", "NJ", "NM", "NY", "NC", "ND", "OH", "OK", "OR",
+			"PA", "RI", "SC", "SD", "TN", "TX", "UT", "VT", "VA", "WA", "WV", "WI", "WY"),
+	},
+}
+
+func setOf(values ...string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+
+	return set
+}
+
+// FromRequest trims the request and upper-cases its codes, so saved addresses compare and format the same.
+func FromRequest(userID int64, request models.AddressRequest) models.Address {
+	return models.Address{
+		UserID:     userID,
+		Label:      strings.TrimSpace(request.Label),
+		Recipient:  strings.TrimSpace(request.Recipient),
+		Phone:      strings.TrimSpace(request.Phone),
+		Line1:      strings.TrimSpace(request.Line1),
+		Line2:      strings.TrimSpace(request.Line2),
+		City:       strings.TrimSpace(request.City),
+		Region:     strings.ToUpper(strings.TrimSpace(request.Region)),
+		PostalCode: strings.ToUpper(strings.TrimSpace(request.PostalCode)),
+		Country:    strings.ToUpper(strings.TrimSpace(request.Country)),
+		IsDefault:  request.IsDefault,
+	}
+}
+
+// Validate checks the fields every address needs, then the rules of its country.
+func Validate(address models.Address) error {
+	// country has its own format check below
+	required := []struct {
+		field string
+		value string
+		max   int
+	}{
+		{"recipient", address.Recipient, 100},
+		{"phone", address.Phone, 20},
+		{"line1", address.Line1, 255},
+		{"city", address.City, 100},
+		{"country", address.Country, 0},
+	}
+
+	for _, field := range required {
+		if field.value == "" {
+			return invalid(field.field, "is required")
+		}
+
+		if field.max > 0 && len(field.value) > field.max {
+			return invalid(field.field, "is too long")
+		}
+	}
+
+	if len(address.Label) > 50 {
+		return invalid("label", "is too long")
+	}
+
+	if len(address.Line2) > 255 {
+		return invalid("line2", "is too long")
+	}
+
+	if !phoneNumber.MatchString(address.Phone) {
+		return invalid("phone", "is not a phone number")
+	}
+
+	err := ValidateDestination(address.Country, address.Region)
+	if err != nil {
+		return err
+	}
+
+	countryRule, isExist := rules[address.Country]
+	if !isExist {
+		if !anyPostalCode.MatchString(address.PostalCode) {
+			return invalid("postal_code", "is not valid")
+		}
+
+		return nil
+	}
+
+	if !countryRule.postalCode.MatchString(address.PostalCode) {
+		return invalid("postal_code", "is not valid for "+address.Country)
+	}
+
+	return nil
+}
+
+// ValidateDestination checks the country and region an order ships to, which decide its tax and
+// shipping. A country that divides its addresses into regions needs one of them.
+func ValidateDestination(country, region string) error {
+	if !countryCode.MatchString(country) {
+		return invalid("country", "must be an ISO 3166-1 alpha-2 code")
+	}
+
+	if region != "" && !regionCode.MatchString(region) {
+		return invalid("region", "must be an ISO 3166-2 subdivision code")
+	}
+
+	countryRule, isExist := rules[country]
+	if !isExist {
+		return nil
+	}
+
+	if countryRule.requireRegion && region == "" {
+		return invalid("region", "is required for "+country)
+	}
+
+	if countryRule.regions != nil && region != "" && !countryRule.regions[region] {
+		return invalid("region", "is not a region of "+country)
+	}
+
+	return nil
+}
+
+// Format writes the address on one line, the way it was stored on orders placed before addresses
+// were structured.
+func Format(address models.Address) string {
+	parts := []string{address.Recipient, address.Phone, address.Line1, address.Line2, address.City}
+	parts = append(parts, strings.TrimSpace(address.Region+" "+address.PostalCode), address.Country)
+
+	lines := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			lines = append(lines, part)
+		}
+	}
+
+	return strings.Join(lines, ", ")
+}
+
+func invalid(field, message string) error {
+	return &constant.AddressValidationError{
+		Field:   field,
+		Message: message,
+	}
+}
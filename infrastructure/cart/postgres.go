@@ -0,0 +1,104 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"order/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// uniqueViolation is the postgres error code of a write that breaks a unique index.
+const uniqueViolation = "23505"
+
+// PostgresStore keeps carts in the carts table, it is where carts live for good.
+type PostgresStore struct {
+	Database *gorm.DB
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{
+		Database: db,
+	}
+}
+
+func (s *PostgresStore) Get(ctx context.Context, cartID string) (models.Cart, error) {
+	return s.take(ctx, "id = ?", cartID)
+}
+
+func (s *PostgresStore) GetByUserID(ctx context.Context, userID int64) (models.Cart, error) {
+	return s.take(ctx, "user_id = ?", userID)
+}
+
+func (s *PostgresStore) take(ctx context.Context, query string, args ...any) (models.Cart, error) {
+	var cart models.Cart
+	err := s.Database.WithContext(ctx).Table("carts").Where(query, args...).Take(&cart).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Cart{}, ErrCartNotFound
+		}
+
+		return models.Cart{}, err
+	}
+
+	return cart, nil
+}
+
+// Create skips a cart that hits the unique index on user_id, the user created one concurrently.
+func (s *PostgresStore) Create(ctx context.Context, cart models.Cart) error {
+	result := s.Database.WithContext(ctx).Table("carts").
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&cart)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrCartExists
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) Save(ctx context.Context, cart models.Cart) (models.Cart, error) {
+	saved := cart
+	saved.Version++
+
+	result := s.Database.WithContext(ctx).Table("carts").
+		Where("id = ? AND version = ?", cart.ID, cart.Version).
+		Select("user_id", "items", "version", "update_time").
+		Updates(&saved)
+	if result.Error != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(result.Error, &pgErr) && pgErr.Code == uniqueViolation {
+			return models.Cart{}, ErrCartExists
+		}
+
+		return models.Cart{}, result.Error
+	}
+
+	// the cart was saved or deleted since it was read
+	if result.RowsAffected == 0 {
+		return models.Cart{}, ErrCartConflict
+	}
+
+	return saved, nil
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, cart models.Cart) error {
+	result := s.Database.WithContext(ctx).Table("carts").
+		Where("id = ? AND version = ?", cart.ID, cart.Version).
+		Delete(&models.Cart{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	// the cart was saved or deleted since it was read
+	if result.RowsAffected == 0 {
+		return ErrCartConflict
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package cart
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"order/infrastructure/log"
+	"order/models"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// RedisStore serves carts from redis and falls back to Postgres, which keeps every cart for good.
+// Writes go to Postgres first; redis only ever holds a copy, so a redis outage slows carts down but
+// loses nothing.
+type RedisStore struct {
+	Client   *redis.Client
+	Fallback *PostgresStore
+	TTL      time.Duration
+}
+
+func NewRedisStore(client *redis.Client, fallback *PostgresStore, ttl time.Duration) *RedisStore {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+
+	return &RedisStore{
+		Client:   client,
+		Fallback: fallback,
+		TTL:      ttl,
+	}
+}
+
+func (s *RedisStore) Get(ctx context.Context, cartID string) (models.Cart, error) {
+	value, err := s.Client.Get(ctx, cartKey(cartID)).Bytes()
+	if err == nil {
+		var cart models.Cart
+		if err = json.Unmarshal(value, &cart); err == nil {
+			return cart, nil
+		}
+	}
+
+	if !errors.Is(err, redis.Nil) {
+		s.logError("s.Client.Get()", cartID, err)
+	}
+
+	cart, err := s.Fallback.Get(ctx, cartID)
+	if err != nil {
+		return models.Cart{}, err
+	}
+
+	s.cache(ctx, cart)
+	return cart, nil
+}
+
+func (s *RedisStore) GetByUserID(ctx context.Context, userID int64) (models.Cart, error) {
+	cartID, err := s.Client.Get(ctx, userCartKey(userID)).Result()
+	if err == nil {
+		return s.Get(ctx, cartID)
+	}
+
+	if !errors.Is(err, redis.Nil) {
+		s.logError("s.Client.Get()", strconv.FormatInt(userID, 10), err)
+	}
+
+	cart, err := s.Fallback.GetByUserID(ctx, userID)
+	if err != nil {
+		return models.Cart{}, err
+	}
+
+	s.cache(ctx, cart)
+	return cart, nil
+}
+
+func (s *RedisStore) Create(ctx context.Context, cart models.Cart) error {
+	err := s.Fallback.Create(ctx, cart)
+	if err != nil {
+		return err
+	}
+
+	s.cache(ctx, cart)
+	return nil
+}
+
+// Save drops the cached copy when Postgres rejects the cart, it may be the stale copy the cart was
+// read from, so the next attempt reads the cart from Postgres.
+func (s *RedisStore) Save(ctx context.Context, cart models.Cart) (models.Cart, error) {
+	saved, err := s.Fallback.Save(ctx, cart)
+	if err != nil {
+		if errors.Is(err, ErrCartConflict) || errors.Is(err, ErrCartExists) {
+			s.Client.Del(ctx, cartKey(cart.ID))
+		}
+
+		return models.Cart{}, err
+	}
+
+	s.cache(ctx, saved)
+	return saved, nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, cart models.Cart) error {
+	err := s.Fallback.Delete(ctx, cart)
+	if err != nil {
+		if errors.Is(err, ErrCartConflict) {
+			s.Client.Del(ctx, cartKey(cart.ID))
+		}
+
+		return err
+	}
+
+	keys := []string{cartKey(cart.ID)}
+	if cart.UserID != 0 {
+		keys = append(keys, userCartKey(cart.UserID))
+	}
+
+	err = s.Client.Del(ctx, keys...).Err()
+	if err != nil {
+		s.logError("s.Client.Del()", cart.ID, err)
+	}
+
+	return nil
+}
+
+// cache copies the cart to redis. A failed copy is dropped so a stale one is never served.
+func (s *RedisStore) cache(ctx context.Context, cart models.Cart) {
+	value, err := json.Marshal(cart)
+	if err != nil {
+		s.logError("json.Marshal()", cart.ID, err)
+		return
+	}
+
+	pipe := s.Client.TxPipeline()
+	pipe.Set(ctx, cartKey(cart.ID), value, s.TTL)
+	if cart.UserID != 0 {
+		pipe.Set(ctx, userCartKey(cart.UserID), cart.ID, s.TTL)
+	}
+
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		s.logError("pipe.Exec()", cart.ID, err)
+		s.Client.Del(ctx, cartKey(cart.ID))
+	}
+}
+
+func (s *RedisStore) logError(caller, key string, err error) {
+	log.Logger.WithFields(logrus.Fields{
+		"err": err.Error(),
+		"key": key,
+	}).Warnf("cart %s got error, using postgres", caller)
+}
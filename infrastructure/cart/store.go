@@ -0,0 +1,46 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"order/models"
+	"time"
+)
+
+const (
+	StoreRedis    = "redis"
+	StorePostgres = "postgres"
+)
+
+const DefaultCacheTTL = 7 * 24 * time.Hour
+
+var (
+	ErrCartNotFound = errors.New("cart not found")
+	ErrCartConflict = errors.New("cart was changed by another request")
+	ErrCartExists   = errors.New("user already has a cart")
+)
+
+// Store keeps carts by id, and the cart of every signed-in user.
+type Store interface {
+	Get(ctx context.Context, cartID string) (models.Cart, error)
+	// GetByUserID returns the cart owned by the user, ErrCartNotFound when the user has none.
+	GetByUserID(ctx context.Context, userID int64) (models.Cart, error)
+	// Create adds a new cart, ErrCartExists when its user already has one.
+	Create(ctx context.Context, cart models.Cart) error
+	// Save writes the cart only if it is still at the version it was read at, and returns it at its
+	// new version. ErrCartConflict means another request saved it first and the change must be
+	// made again on a fresh copy, ErrCartExists that it was given to a user who already has a cart.
+	Save(ctx context.Context, cart models.Cart) (models.Cart, error)
+	// Delete removes the cart only if it is still at the version it was read at, ErrCartConflict
+	// means it was changed since and is kept.
+	Delete(ctx context.Context, cart models.Cart) error
+}
+
+func cartKey(cartID string) string {
+	return fmt.Sprintf("cart:%s", cartID)
+}
+
+func userCartKey(userID int64) string {
+	return fmt.Sprintf("cart:user:%d", userID)
+}
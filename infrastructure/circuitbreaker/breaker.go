@@ -0,0 +1,147 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half_open"
+)
+
+type Config struct {
+	// FailureRate opens the breaker once this share of the requests in a window failed.
+	FailureRate float64
+	// MinRequests is how many requests a window needs before FailureRate is considered.
+	MinRequests int
+	// Window is the length of the counting window while closed.
+	Window time.Duration
+	// OpenTimeout is how long the breaker fails fast before letting a probe through.
+	OpenTimeout time.Duration
+}
+
+// Breaker is a count based circuit breaker. While closed it counts outcomes per fixed window, once
+// open it rejects calls until OpenTimeout elapsed and then lets a single probe decide whether to close.
+type Breaker struct {
+	cfg Config
+	now func() time.Time
+
+	mu          sync.Mutex
+	state       string
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probing     bool
+}
+
+func New(cfg Config) *Breaker {
+	return &Breaker{
+		cfg:         cfg,
+		now:         time.Now,
+		state:       StateClosed,
+		windowStart: time.Now(),
+	}
+}
+
+func (b *Breaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// Allow reports whether a call may go through. Every allowed call must be settled with Success,
+// Failure or Ignore.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+
+	switch b.state {
+	case StateOpen:
+		if now.Sub(b.openedAt) < b.cfg.OpenTimeout {
+			return ErrOpen
+		}
+
+		b.state = StateHalfOpen
+		b.probing = true
+		return nil
+	case StateHalfOpen:
+		if b.probing {
+			return ErrOpen
+		}
+
+		b.probing = true
+		return nil
+	default:
+		if now.Sub(b.windowStart) >= b.cfg.Window {
+			b.resetWindow(now)
+		}
+
+		return nil
+	}
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen {
+		b.state = StateClosed
+		b.probing = false
+		b.resetWindow(b.now())
+		return
+	}
+
+	b.requests++
+}
+
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+
+	if b.state == StateHalfOpen {
+		b.trip(now)
+		return
+	}
+
+	b.requests++
+	b.failures++
+
+	if b.requests >= b.cfg.MinRequests && float64(b.failures)/float64(b.requests) >= b.cfg.FailureRate {
+		b.trip(now)
+	}
+}
+
+// Ignore settles an allowed call whose outcome says nothing about the remote side, such as a call
+// cancelled by its caller.
+func (b *Breaker) Ignore() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen {
+		b.probing = false
+	}
+}
+
+func (b *Breaker) trip(now time.Time) {
+	b.state = StateOpen
+	b.openedAt = now
+	b.probing = false
+	b.resetWindow(now)
+}
+
+func (b *Breaker) resetWindow(now time.Time) {
+	b.windowStart = now
+	b.requests = 0
+	b.failures = 0
+}
@@ -0,0 +1,167 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+const (
+	actionAllow   = "allow"
+	actionSuccess = "success"
+	actionFailure = "failure"
+	actionIgnore  = "ignore"
+)
+
+type step struct {
+	// advance moves the clock forward before the action.
+	advance   time.Duration
+	action    string
+	wantErr   error
+	wantState string
+}
+
+func calls(action string, count int, wantState string) []step {
+	steps := make([]step, 0, 2*count)
+	for i := 0; i < count; i++ {
+		steps = append(steps, step{action: actionAllow}, step{action: action, wantState: wantState})
+	}
+
+	return steps
+}
+
+func sequence(parts ...[]step) []step {
+	var steps []step
+	for _, part := range parts {
+		steps = append(steps, part...)
+	}
+
+	return steps
+}
+
+func TestBreaker(t *testing.T) {
+	cfg := Config{
+		FailureRate: 0.5,
+		MinRequests: 4,
+		Window:      10 * time.Second,
+		OpenTimeout: 5 * time.Second,
+	}
+
+	// two successes and two failures reach both MinRequests and FailureRate, only a failure trips
+	trip := sequence(calls(actionSuccess, 2, StateClosed), calls(actionFailure, 1, StateClosed), calls(actionFailure, 1, StateOpen))
+	probe := sequence(trip, []step{{advance: cfg.OpenTimeout, action: actionAllow, wantState: StateHalfOpen}})
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name:  "stays closed below the minimum requests",
+			steps: calls(actionFailure, 3, StateClosed),
+		},
+		{
+			name: "stays closed below the failure rate",
+			steps: sequence(
+				calls(actionFailure, 1, StateClosed),
+				calls(actionSuccess, 5, StateClosed),
+				calls(actionFailure, 1, StateClosed),
+			),
+		},
+		{
+			name:  "opens at the failure rate once the minimum requests is reached",
+			steps: sequence(trip, []step{{action: actionAllow, wantErr: ErrOpen, wantState: StateOpen}}),
+		},
+		{
+			name:  "opens on failures alone once the minimum requests is reached",
+			steps: sequence(calls(actionFailure, 3, StateClosed), calls(actionFailure, 1, StateOpen)),
+		},
+		{
+			name: "a new window forgets the old counts",
+			steps: sequence(
+				calls(actionFailure, 3, StateClosed),
+				[]step{{advance: cfg.Window, action: actionAllow, wantState: StateClosed}},
+				[]step{{action: actionFailure, wantState: StateClosed}},
+				calls(actionFailure, 2, StateClosed),
+				calls(actionFailure, 1, StateOpen),
+			),
+		},
+		{
+			name: "the window is not reset before it ends",
+			steps: sequence(
+				calls(actionFailure, 3, StateClosed),
+				[]step{{advance: cfg.Window - time.Millisecond, action: actionAllow, wantState: StateClosed}},
+				[]step{{action: actionFailure, wantState: StateOpen}},
+			),
+		},
+		{
+			name: "rejects calls until the open timeout elapsed",
+			steps: sequence(trip, []step{
+				{advance: cfg.OpenTimeout - time.Millisecond, action: actionAllow, wantErr: ErrOpen, wantState: StateOpen},
+				{advance: time.Millisecond, action: actionAllow, wantState: StateHalfOpen},
+			}),
+		},
+		{
+			name: "lets a single probe through while half open",
+			steps: sequence(probe, []step{
+				{action: actionAllow, wantErr: ErrOpen, wantState: StateHalfOpen},
+				{advance: cfg.OpenTimeout, action: actionAllow, wantErr: ErrOpen, wantState: StateHalfOpen},
+			}),
+		},
+		{
+			name: "a successful probe closes with fresh counts",
+			steps: sequence(
+				probe,
+				[]step{{action: actionSuccess, wantState: StateClosed}},
+				calls(actionFailure, 3, StateClosed),
+				calls(actionFailure, 1, StateOpen),
+			),
+		},
+		{
+			name: "a failed probe opens for another timeout",
+			steps: sequence(probe, []step{
+				{action: actionFailure, wantState: StateOpen},
+				{advance: cfg.OpenTimeout - time.Millisecond, action: actionAllow, wantErr: ErrOpen, wantState: StateOpen},
+				{advance: time.Millisecond, action: actionAllow, wantState: StateHalfOpen},
+			}),
+		},
+		{
+			name: "an ignored probe lets the next call probe",
+			steps: sequence(probe, []step{
+				{action: actionIgnore, wantState: StateHalfOpen},
+				{action: actionAllow, wantState: StateHalfOpen},
+				{action: actionAllow, wantErr: ErrOpen, wantState: StateHalfOpen},
+			}),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			breaker := New(cfg)
+			breaker.now = func() time.Time { return now }
+			breaker.windowStart = now
+
+			for i, s := range tc.steps {
+				now = now.Add(s.advance)
+
+				switch s.action {
+				case actionAllow:
+					err := breaker.Allow()
+					if !errors.Is(err, s.wantErr) {
+						t.Fatalf("step %d: Allow() error = %v, want %v", i, err, s.wantErr)
+					}
+				case actionSuccess:
+					breaker.Success()
+				case actionFailure:
+					breaker.Failure()
+				case actionIgnore:
+					breaker.Ignore()
+				}
+
+				if s.wantState != "" && breaker.State() != s.wantState {
+					t.Fatalf("step %d: after %s state = %s, want %s", i, s.action, breaker.State(), s.wantState)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package constant
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrOrderNotFound = errors.New("order not found")
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	ErrInvalidCheckout     = errors.New("invalid checkout")
+	ErrIdempotencyInFlight = errors.New("a request with the same idempotency token is still in progress")
+
+	ErrAddressNotFound     = errors.New("address not found")
+	ErrInvalidAddress      = errors.New("invalid address")
+	ErrAddressLimitReached = errors.New("address book is full")
+
+	ErrInvalidCart = errors.New("invalid cart")
+)
+
+// CheckoutValidationError rejects a cart that can never be checked out as submitted.
+type CheckoutValidationError struct {
+	Message string
+}
+
+func (e *CheckoutValidationError) Error() string {
+	return e.Message
+}
+
+func (e *CheckoutValidationError) Is(target error) bool {
+	return target == ErrInvalidCheckout
+}
+
+// CouponUnavailableError rejects a coupon code that cannot be applied to the cart.
+type CouponUnavailableError struct {
+	Code   string
+	Reason string
+}
+
+func (e *CouponUnavailableError) Error() string {
+	return fmt.Sprintf("coupon %s %s", e.Code, e.Reason)
+}
+
+func (e *CouponUnavailableError) Is(target error) bool {
+	return target == ErrInvalidCheckout
+}
+
+// AddressValidationError rejects an address field that does not follow the rules of its country.
+type AddressValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *AddressValidationError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
+
+func (e *AddressValidationError) Is(target error) bool {
+	return target == ErrInvalidAddress
+}
@@ -6,6 +6,7 @@ const (
 	OrderStatusCompleted  = 2
 	OrderStatusCancelled  = 3
 	OrderStatusFailed     = 4
+	OrderStatusExpired    = 5
 )
 
 var OrderStatusTranslated = map[int]string{
@@ -14,4 +15,44 @@ var OrderStatusTranslated = map[int]string{
 	OrderStatusCompleted:  "Completed",
 	OrderStatusCancelled:  "Cancelled",
 	OrderStatusFailed:     "Failed",
+	OrderStatusExpired:    "Expired",
 }
+
+const (
+	SortDirectionAsc  = "asc"
+	SortDirectionDesc = "desc"
+)
+
+const (
+	OrderHistoryDefaultLimit = 20
+	OrderHistoryMaxLimit     = 100
+)
+
+const (
+	CouponTypePercentage = "percentage"
+	CouponTypeFixed      = "fixed"
+	CouponTypeBuyXGetY   = "buy_x_get_y"
+)
+
+// cart limits, the quantity limit matches checkout
+const (
+	MaxCartItems        = 100
+	MaxCartItemQuantity = 1000
+)
+
+// CartSaveAttempts is how many times a cart change is tried when other requests keep saving the cart first.
+const CartSaveAttempts = 3
+
+// MaxAddressesPerUser caps the address book of a user.
+const MaxAddressesPerUser = 20
+
+// actor types recorded in order_status_history
+const (
+	ActorTypeUser    = "user"
+	ActorTypeSystem  = "system"
+	ActorTypePayment = "payment"
+)
+
+type contextKey string
+
+const ContextKeyRequestID contextKey = "request_id"
@@ -0,0 +1,66 @@
+package constant
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var ErrInvalidStatusTransition = errors.New("invalid order status transition")
+
+// OrderStatusTransitions lists, for every status, the statuses an order may move to next.
+// Statuses mapped to an empty list are terminal.
+var OrderStatusTransitions = map[int][]int{
+	OrderStatusCreated:    {OrderStatusProcessing, OrderStatusCompleted, OrderStatusCancelled, OrderStatusFailed, OrderStatusExpired},
+	OrderStatusProcessing: {OrderStatusCompleted, OrderStatusCancelled, OrderStatusFailed, OrderStatusExpired},
+	OrderStatusCompleted:  {},
+	OrderStatusCancelled:  {},
+	OrderStatusFailed:     {},
+	OrderStatusExpired:    {},
+}
+
+type InvalidStatusTransitionError struct {
+	OrderID int64
+	From    int
+	To      int
+}
+
+func (e *InvalidStatusTransitionError) Error() string {
+	return fmt.Sprintf("order %d cannot move from %s to %s", e.OrderID, OrderStatusName(e.From), OrderStatusName(e.To))
+}
+
+func (e *InvalidStatusTransitionError) Is(target error) bool {
+	return target == ErrInvalidStatusTransition
+}
+
+func OrderStatusName(status int) string {
+	name, ok := OrderStatusTranslated[status]
+	if !ok {
+		return fmt.Sprintf("Unknown(%d)", status)
+	}
+
+	return name
+}
+
+func CanTransitionOrderStatus(from, to int) bool {
+	for _, next := range OrderStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OrderStatusSources returns every status from which an order may legally move to the given status.
+func OrderStatusSources(to int) []int {
+	sources := make([]int, 0)
+	for from := range OrderStatusTransitions {
+		if CanTransitionOrderStatus(from, to) {
+			sources = append(sources, from)
+		}
+	}
+
+	sort.Ints(sources)
+	return sources
+}
@@ -0,0 +1,109 @@
+package constant
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+var allOrderStatuses = []int{
+	OrderStatusCreated,
+	OrderStatusProcessing,
+	OrderStatusCompleted,
+	OrderStatusCancelled,
+	OrderStatusFailed,
+	OrderStatusExpired,
+}
+
+var terminalOrderStatuses = []int{
+	OrderStatusCompleted,
+	OrderStatusCancelled,
+	OrderStatusFailed,
+	OrderStatusExpired,
+}
+
+type transitionTest struct {
+	name string
+	from int
+	to   int
+	want bool
+}
+
+func TestCanTransitionOrderStatus(t *testing.T) {
+	tests := []transitionTest{
+		{name: "created to processing", from: OrderStatusCreated, to: OrderStatusProcessing, want: true},
+		{name: "created to completed", from: OrderStatusCreated, to: OrderStatusCompleted, want: true},
+		{name: "created to cancelled", from: OrderStatusCreated, to: OrderStatusCancelled, want: true},
+		{name: "created to failed", from: OrderStatusCreated, to: OrderStatusFailed, want: true},
+		{name: "created to expired", from: OrderStatusCreated, to: OrderStatusExpired, want: true},
+		{name: "processing to completed", from: OrderStatusProcessing, to: OrderStatusCompleted, want: true},
+		{name: "processing to cancelled", from: OrderStatusProcessing, to: OrderStatusCancelled, want: true},
+		{name: "processing to failed", from: OrderStatusProcessing, to: OrderStatusFailed, want: true},
+		{name: "processing to expired", from: OrderStatusProcessing, to: OrderStatusExpired, want: true},
+		{name: "processing back to created", from: OrderStatusProcessing, to: OrderStatusCreated},
+		{name: "created to created", from: OrderStatusCreated, to: OrderStatusCreated},
+		{name: "processing to processing", from: OrderStatusProcessing, to: OrderStatusProcessing},
+		{name: "unknown source", from: 99, to: OrderStatusProcessing},
+		{name: "unknown target", from: OrderStatusCreated, to: 99},
+	}
+
+	// a terminal status never moves again, not even to itself
+	for _, from := range terminalOrderStatuses {
+		for _, to := range allOrderStatuses {
+			tests = append(tests, transitionTest{name: OrderStatusName(from) + " to " + OrderStatusName(to), from: from, to: to})
+		}
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := CanTransitionOrderStatus(tc.from, tc.to)
+			if got != tc.want {
+				t.Errorf("CanTransitionOrderStatus(%d, %d) = %t, want %t", tc.from, tc.to, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOrderStatusTransitionsCoverEveryStatus(t *testing.T) {
+	for _, status := range allOrderStatuses {
+		if _, isExist := OrderStatusTransitions[status]; !isExist {
+			t.Errorf("status %s has no entry in OrderStatusTransitions", OrderStatusName(status))
+		}
+	}
+}
+
+func TestOrderStatusSources(t *testing.T) {
+	tests := []struct {
+		to   int
+		want []int
+	}{
+		{to: OrderStatusCreated, want: []int{}},
+		{to: OrderStatusProcessing, want: []int{OrderStatusCreated}},
+		{to: OrderStatusCompleted, want: []int{OrderStatusCreated, OrderStatusProcessing}},
+		{to: OrderStatusCancelled, want: []int{OrderStatusCreated, OrderStatusProcessing}},
+		{to: OrderStatusFailed, want: []int{OrderStatusCreated, OrderStatusProcessing}},
+		{to: OrderStatusExpired, want: []int{OrderStatusCreated, OrderStatusProcessing}},
+	}
+
+	for _, tc := range tests {
+		t.Run(OrderStatusName(tc.to), func(t *testing.T) {
+			got := OrderStatusSources(tc.to)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("OrderStatusSources(%s) = %v, want %v", OrderStatusName(tc.to), got, tc.want)
+			}
+		})
+	}
+}
+
+func TestInvalidStatusTransitionError(t *testing.T) {
+	err := error(&InvalidStatusTransitionError{OrderID: 7, From: OrderStatusCompleted, To: OrderStatusCancelled})
+
+	if !errors.Is(err, ErrInvalidStatusTransition) {
+		t.Errorf("errors.Is(%v, ErrInvalidStatusTransition) = false, want true", err)
+	}
+
+	want := "order 7 cannot move from " + OrderStatusName(OrderStatusCompleted) + " to " + OrderStatusName(OrderStatusCancelled)
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
@@ -0,0 +1,13 @@
+package constant
+
+const (
+	TopicOrderCancelled          = "order.cancelled"
+	TopicOrderExpired            = "order.expired"
+	TopicOrderCreated            = "order.created"
+	TopicProductStockUpdate      = "stock.update"
+	TopicProductStockRollback    = "stock.rollback"
+	TopicStockReservationConfirm = "stock.reservation.confirm"
+	TopicStockReservationRelease = "stock.reservation.release"
+	TopicPaymentSuccess          = "payment.success"
+	TopicPaymentFailed           = "payment.failed"
+)
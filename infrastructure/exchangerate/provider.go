@@ -0,0 +1,145 @@
+package exchangerate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"order/infrastructure/money"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	ProviderStatic = "static"
+	ProviderFile   = "file"
+)
+
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// Quote is the rate of Currency at Time: one unit of Currency is worth Rate units of Base.
+type Quote struct {
+	Currency string
+	Base     string
+	Rate     money.Rate
+	Time     time.Time
+}
+
+// Provider returns exchange rates into the base currency orders are reported in.
+type Provider interface {
+	BaseCurrency() string
+	Quote(ctx context.Context, currency string) (Quote, error)
+}
+
+// StaticProvider serves a fixed rate table, for local testing and markets with pegged prices.
+type StaticProvider struct {
+	base  string
+	rates map[string]money.Rate
+	time  time.Time
+}
+
+// NewStaticProvider rejects a base or quoted currency that amounts cannot be kept in.
+func NewStaticProvider(base string, rates map[string]money.Rate) (*StaticProvider, error) {
+	base = strings.ToUpper(base)
+	if base == "" {
+		base = money.DefaultCurrency
+	}
+
+	err := money.CheckCurrency(base)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedCurrency, err)
+	}
+
+	table := make(map[string]money.Rate, len(rates)+1)
+	for currency, rate := range rates {
+		currency = strings.ToUpper(currency)
+
+		err = money.CheckCurrency(currency)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrUnsupportedCurrency, err)
+		}
+
+		table[currency] = rate
+	}
+	table[base] = money.OneRate()
+
+	return &StaticProvider{
+		base:  base,
+		rates: table,
+		time:  time.Now(),
+	}, nil
+}
+
+func (p *StaticProvider) BaseCurrency() string {
+	return p.base
+}
+
+func (p *StaticProvider) Quote(ctx context.Context, currency string) (Quote, error) {
+	currency = strings.ToUpper(currency)
+
+	rate, isExist := p.rates[currency]
+	if !isExist {
+		return Quote{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+	}
+
+	return Quote{
+		Currency: currency,
+		Base:     p.base,
+		Rate:     rate,
+		Time:     p.time,
+	}, nil
+}
+
+// ParseRates reads a "currency:rate" comma list such as "USD:16250,SGD:12100.5".
+func ParseRates(value string) (map[string]money.Rate, error) {
+	rates := make(map[string]money.Rate)
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		currency, rateValue, isFound := strings.Cut(entry, ":")
+		if !isFound {
+			return nil, fmt.Errorf("invalid exchange rate %q", entry)
+		}
+
+		rate, err := money.ParseRate(rateValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exchange rate %q: %w", entry, err)
+		}
+
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+		err = money.CheckCurrency(currency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exchange rate %q: %w", entry, err)
+		}
+
+		rates[currency] = rate
+	}
+
+	return rates, nil
+}
+
+type rateFile struct {
+	Base  string                `json:"base"`
+	Rates map[string]money.Rate `json:"rates"`
+}
+
+// NewFileProvider loads a rate table from a JSON file shaped like {"base": "IDR", "rates": {"USD": "16250"}}.
+func NewFileProvider(path string) (*StaticProvider, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file rateFile
+	err = json.Unmarshal(content, &file)
+	if err != nil {
+		return nil, fmt.Errorf("parse exchange rate file %s: %w", path, err)
+	}
+
+	return NewStaticProvider(file.Base, file.Rates)
+}
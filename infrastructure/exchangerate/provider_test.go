@@ -0,0 +1,76 @@
+package exchangerate
+
+import (
+	"context"
+	"errors"
+	"order/infrastructure/money"
+	"testing"
+)
+
+func TestParseRates(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]string
+		wantErr error
+	}{
+		{name: "empty", value: "", want: map[string]string{}},
+		{name: "list", value: "USD:16250, sgd:12100.5,", want: map[string]string{"USD": "16250", "SGD": "12100.5"}},
+		{name: "zero decimal currency", value: "USD:16250,JPY:108", want: map[string]string{"USD": "16250", "JPY": "108"}},
+		{name: "three decimal currency", value: "KWD:52000", wantErr: money.ErrUnsupportedExponent},
+		{name: "unknown currency", value: "ABC:1", wantErr: money.ErrUnknownCurrency},
+		{name: "invalid rate", value: "USD:0", wantErr: money.ErrInvalidRate},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rates, err := ParseRates(tc.value)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ParseRates(%q) error = %v, want %v", tc.value, err, tc.wantErr)
+			}
+
+			if tc.wantErr != nil {
+				return
+			}
+
+			if len(rates) != len(tc.want) {
+				t.Fatalf("ParseRates(%q) = %v, want %v", tc.value, rates, tc.want)
+			}
+
+			for currency, want := range tc.want {
+				if rates[currency].String() != want {
+					t.Errorf("ParseRates(%q)[%s] = %s, want %s", tc.value, currency, rates[currency], want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewStaticProvider(t *testing.T) {
+	usd, _ := money.ParseRate("16250")
+
+	provider, err := NewStaticProvider("idr", map[string]money.Rate{"usd": usd})
+	if err != nil {
+		t.Fatalf("NewStaticProvider() error = %v", err)
+	}
+
+	quote, err := provider.Quote(context.Background(), "USD")
+	if err != nil || quote.Base != "IDR" || quote.Rate.String() != "16250" {
+		t.Errorf("Quote(USD) = %+v, %v", quote, err)
+	}
+
+	quote, err = provider.Quote(context.Background(), "IDR")
+	if err != nil || quote.Rate.String() != "1" {
+		t.Errorf("Quote(IDR) = %+v, %v", quote, err)
+	}
+
+	_, err = provider.Quote(context.Background(), "EUR")
+	if !errors.Is(err, ErrUnsupportedCurrency) {
+		t.Errorf("Quote(EUR) error = %v, want %v", err, ErrUnsupportedCurrency)
+	}
+
+	_, err = NewStaticProvider("KWD", nil)
+	if !errors.Is(err, ErrUnsupportedCurrency) || !errors.Is(err, money.ErrUnsupportedExponent) {
+		t.Errorf("NewStaticProvider(KWD) error = %v, want %v", err, money.ErrUnsupportedExponent)
+	}
+}
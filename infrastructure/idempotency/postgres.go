@@ -0,0 +1,105 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"order/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresStore keeps idempotency keys in the order_request_log table.
+type PostgresStore struct {
+	Database *gorm.DB
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{
+		Database: db,
+	}
+}
+
+func (s *PostgresStore) Reserve(ctx context.Context, key string, ttl time.Duration) (Record, bool, error) {
+	now := time.Now()
+
+	// drop an expired reservation first so the key can be claimed again
+	err := s.Database.WithContext(ctx).Table("order_request_log").
+		Where("idempotency_key = ? AND expire_time < ?", key, now).
+		Delete(&models.OrderRequestLog{}).Error
+	if err != nil {
+		return Record{}, false, err
+	}
+
+	owner := uuid.NewString()
+	log := models.OrderRequestLog{
+		IdempotencyKey: key,
+		Status:         StatusInProgress,
+		OwnerToken:     &owner,
+		CreateTime:     now,
+		ExpireTime:     now.Add(ttl),
+	}
+
+	result := s.Database.WithContext(ctx).Table("order_request_log").
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&log)
+	if result.Error != nil {
+		return Record{}, false, result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		return Record{Status: StatusInProgress, Owner: owner}, true, nil
+	}
+
+	var existing models.OrderRequestLog
+	err = s.Database.WithContext(ctx).Table("order_request_log").Where("idempotency_key = ?", key).Take(&existing).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return Record{Status: StatusInProgress}, false, nil
+		}
+
+		return Record{}, false, err
+	}
+
+	return Record{
+		Status:  existing.Status,
+		OrderID: existing.OrderID,
+	}, false, nil
+}
+
+func (s *PostgresStore) Complete(ctx context.Context, key, owner string, record Record, ttl time.Duration) error {
+	result := s.Database.WithContext(ctx).Table("order_request_log").
+		Where("idempotency_key = ? AND owner_token = ?", key, owner).
+		Updates(map[string]interface{}{
+			"status":      record.Status,
+			"order_id":    record.OrderID,
+			"owner_token": nil,
+			"expire_time": time.Now().Add(ttl),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotOwner
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) Release(ctx context.Context, key, owner string) error {
+	result := s.Database.WithContext(ctx).Table("order_request_log").
+		Where("idempotency_key = ? AND owner_token = ?", key, owner).
+		Delete(&models.OrderRequestLog{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotOwner
+	}
+
+	return nil
+}
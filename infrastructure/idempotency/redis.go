@@ -0,0 +1,111 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// completeScript overwrites the record only while it is still held by ARGV[1].
+var completeScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if not current or cjson.decode(current).owner ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+// releaseScript deletes the record only while it is still held by ARGV[1].
+var releaseScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if not current or cjson.decode(current).owner ~= ARGV[1] then
+	return 0
+end
+return redis.call('DEL', KEYS[1])
+`)
+
+type RedisStore struct {
+	Client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{
+		Client: client,
+	}
+}
+
+func (s *RedisStore) Reserve(ctx context.Context, key string, ttl time.Duration) (Record, bool, error) {
+	reservation := Record{Status: StatusInProgress, Owner: uuid.NewString()}
+	value, err := json.Marshal(reservation)
+	if err != nil {
+		return Record{}, false, err
+	}
+
+	// the key may expire between SET NX and GET, in that case try to claim it once more
+	for attempt := 0; attempt < 2; attempt++ {
+		reserved, err := s.Client.SetNX(ctx, key, value, ttl).Result()
+		if err != nil {
+			return Record{}, false, err
+		}
+
+		if reserved {
+			return reservation, true, nil
+		}
+
+		existing, err := s.Client.Get(ctx, key).Bytes()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+
+			return Record{}, false, err
+		}
+
+		var record Record
+		err = json.Unmarshal(existing, &record)
+		if err != nil {
+			return Record{}, false, err
+		}
+
+		record.Owner = ""
+		return record, false, nil
+	}
+
+	return Record{Status: StatusInProgress}, false, nil
+}
+
+func (s *RedisStore) Complete(ctx context.Context, key, owner string, record Record, ttl time.Duration) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	updated, err := completeScript.Run(ctx, s.Client, []string{key}, owner, value, ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+
+	if updated == 0 {
+		return ErrNotOwner
+	}
+
+	return nil
+}
+
+func (s *RedisStore) Release(ctx context.Context, key, owner string) error {
+	deleted, err := releaseScript.Run(ctx, s.Client, []string{key}, owner).Int()
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return ErrNotOwner
+	}
+
+	return nil
+}
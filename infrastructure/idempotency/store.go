@@ -0,0 +1,53 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	StoreRedis    = "redis"
+	StorePostgres = "postgres"
+)
+
+const (
+	DefaultTTL     = 24 * time.Hour
+	DefaultLockTTL = 30 * time.Second
+)
+
+const (
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+)
+
+// ErrNotOwner is returned when a key is completed or released by a request that no longer holds it,
+// usually because its reservation expired and another request claimed the key.
+var ErrNotOwner = errors.New("idempotency key is held by another request")
+
+// Record is what a store keeps for an idempotency key: whether the first request is still running and,
+// once it finished, the response to replay.
+type Record struct {
+	Status  string `json:"status"`
+	OrderID int64  `json:"order_id"`
+	// Owner is the token of the request holding an in-progress reservation.
+	Owner string `json:"owner,omitempty"`
+}
+
+// Store reserves idempotency keys and keeps the response of the request that owns them.
+type Store interface {
+	// Reserve atomically claims key for ttl. The returned record carries the owner token to pass to
+	// Complete or Release. When the key is already taken it returns the existing record and false.
+	Reserve(ctx context.Context, key string, ttl time.Duration) (Record, bool, error)
+	// Complete stores the final record of a key reserved with owner for ttl, it returns ErrNotOwner
+	// when the key is no longer held by owner.
+	Complete(ctx context.Context, key, owner string, record Record, ttl time.Duration) error
+	// Release frees a key reserved with owner so the request can be retried, it returns ErrNotOwner
+	// when the key is no longer held by owner.
+	Release(ctx context.Context, key, owner string) error
+}
+
+func CheckoutKey(userID int64, token string) string {
+	return fmt.Sprintf("idempotency:checkout:%d:%s", userID, token)
+}
@@ -0,0 +1,336 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// lockKey is the Postgres advisory lock id held while migrating, so replicas migrating on startup
+// run one after another.
+const lockKey = 720190002
+
+var ErrChecksumMismatch = errors.New("applied migration was modified")
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// AppliedMigration is a row of schema_migrations.
+type AppliedMigration struct {
+	Version     int64 `gorm:"primaryKey"`
+	Name        string
+	Checksum    string
+	AppliedTime time.Time
+}
+
+type Status struct {
+	Migration
+	Applied     bool
+	AppliedTime time.Time
+	Modified    bool
+}
+
+type Migrator struct {
+	Database   *gorm.DB
+	Migrations []Migration
+}
+
+func NewMigrator(db *gorm.DB, files fs.FS) (*Migrator, error) {
+	migrations, err := Load(files)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		Database:   db,
+		Migrations: migrations,
+	}, nil
+}
+
+// Load reads the migrations of files sorted by version. Every version needs an up file; the
+// checksum covers the up file only, since it is what was applied.
+func Load(files fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s", entry.Name())
+		}
+
+		content, err := fs.ReadFile(files, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, isExist := byVersion[version]
+		if !isExist {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+
+		sum := sha256.Sum256([]byte(migration.Up))
+		migration.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies every pending migration in a single transaction and returns them. Nothing is applied
+// when an applied migration was modified since.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+
+	err := m.withLock(ctx, func(tx *gorm.DB) error {
+		appliedByVersion, err := m.verify(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.pending(appliedByVersion) {
+			err = tx.WithContext(ctx).Exec(migration.Up).Error
+			if err != nil {
+				return fmt.Errorf("apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			err = tx.WithContext(ctx).Table("schema_migrations").Create(&AppliedMigration{
+				Version:     migration.Version,
+				Name:        migration.Name,
+				Checksum:    migration.Checksum,
+				AppliedTime: time.Now(),
+			}).Error
+			if err != nil {
+				return err
+			}
+
+			applied = append(applied, migration)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+
+	err := m.withLock(ctx, func(tx *gorm.DB) error {
+		appliedByVersion, err := m.verify(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		toRevert, err := m.revertible(appliedByVersion, steps)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range toRevert {
+			err = tx.WithContext(ctx).Exec(migration.Down).Error
+			if err != nil {
+				return fmt.Errorf("revert migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			err = tx.WithContext(ctx).Table("schema_migrations").Where("version = ?", migration.Version).Delete(&AppliedMigration{}).Error
+			if err != nil {
+				return err
+			}
+
+			reverted = append(reverted, migration)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reverted, nil
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var result []Status
+
+	err := m.withLock(ctx, func(tx *gorm.DB) error {
+		appliedByVersion, err := m.appliedMigrations(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		result = m.statuses(appliedByVersion)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return m.Database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("SELECT pg_advisory_xact_lock(?)", lockKey).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version bigint PRIMARY KEY,
+			name varchar(255) not null,
+			checksum varchar(64) not null,
+			applied_time timestamp default current_timestamp
+		)`).Error
+		if err != nil {
+			return err
+		}
+
+		return fn(tx)
+	})
+}
+
+// verify returns the applied migrations, failing when one of them no longer matches its file.
+func (m *Migrator) verify(ctx context.Context, tx *gorm.DB) (map[int64]AppliedMigration, error) {
+	appliedByVersion, err := m.appliedMigrations(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.checkChecksums(appliedByVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return appliedByVersion, nil
+}
+
+func (m *Migrator) checkChecksums(appliedByVersion map[int64]AppliedMigration) error {
+	for _, migration := range m.Migrations {
+		applied, isExist := appliedByVersion[migration.Version]
+		if isExist && applied.Checksum != migration.Checksum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+		}
+	}
+
+	return nil
+}
+
+// pending returns the migrations not applied yet, oldest first.
+func (m *Migrator) pending(appliedByVersion map[int64]AppliedMigration) []Migration {
+	var result []Migration
+	for _, migration := range m.Migrations {
+		if _, isExist := appliedByVersion[migration.Version]; !isExist {
+			result = append(result, migration)
+		}
+	}
+
+	return result
+}
+
+// revertible returns the last steps applied migrations, newest first, failing when one of them has
+// no down file.
+func (m *Migrator) revertible(appliedByVersion map[int64]AppliedMigration, steps int) ([]Migration, error) {
+	var result []Migration
+	for index := len(m.Migrations) - 1; index >= 0 && len(result) < steps; index-- {
+		migration := m.Migrations[index]
+		if _, isExist := appliedByVersion[migration.Version]; !isExist {
+			continue
+		}
+
+		if migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+		}
+
+		result = append(result, migration)
+	}
+
+	return result, nil
+}
+
+func (m *Migrator) statuses(appliedByVersion map[int64]AppliedMigration) []Status {
+	result := make([]Status, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		status := Status{Migration: migration}
+
+		if applied, isExist := appliedByVersion[migration.Version]; isExist {
+			status.Applied = true
+			status.AppliedTime = applied.AppliedTime
+			status.Modified = applied.Checksum != migration.Checksum
+		}
+
+		result = append(result, status)
+	}
+
+	return result
+}
+
+func (m *Migrator) appliedMigrations(ctx context.Context, tx *gorm.DB) (map[int64]AppliedMigration, error) {
+	var applied []AppliedMigration
+	err := tx.WithContext(ctx).Table("schema_migrations").Order("version ASC").Find(&applied).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[int64]AppliedMigration, len(applied))
+	for _, migration := range applied {
+		result[migration.Version] = migration
+	}
+
+	return result, nil
+}
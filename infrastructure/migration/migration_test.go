@@ -0,0 +1,329 @@
+package migration
+
+import (
+	"errors"
+	migrations "order/files/migrations"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func testFiles() fstest.MapFS {
+	return fstest.MapFS{
+		"0010_add_index.up.sql":     {Data: []byte("CREATE INDEX idx ON t (a);")},
+		"0010_add_index.down.sql":   {Data: []byte("DROP INDEX idx;")},
+		"0002_create_t.up.sql":      {Data: []byte("CREATE TABLE t (a int);")},
+		"0002_create_t.down.sql":    {Data: []byte("DROP TABLE t;")},
+		"0001_init.up.sql":          {Data: []byte("SELECT 1;")},
+		"0003_no_down.up.sql":       {Data: []byte("ALTER TABLE t ADD COLUMN b int;")},
+		"migrations.go":             {Data: []byte("package migrations")},
+		"README.md":                 {Data: []byte("notes")},
+		"nested/0099_skip.up.sql":   {Data: []byte("SELECT 99;")},
+		"nested/0099_skip.down.sql": {Data: []byte("SELECT 99;")},
+	}
+}
+
+func testMigrator(t *testing.T) *Migrator {
+	t.Helper()
+
+	loaded, err := Load(testFiles())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Migrator{Migrations: loaded}
+}
+
+func applied(m *Migrator, versions ...int64) map[int64]AppliedMigration {
+	result := make(map[int64]AppliedMigration)
+	for _, migration := range m.Migrations {
+		for _, version := range versions {
+			if migration.Version == version {
+				result[version] = AppliedMigration{Version: version, Name: migration.Name, Checksum: migration.Checksum}
+			}
+		}
+	}
+
+	return result
+}
+
+func versionsOf(list []Migration) []int64 {
+	result := make([]int64, 0, len(list))
+	for _, migration := range list {
+		result = append(result, migration.Version)
+	}
+
+	return result
+}
+
+func equalVersions(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestLoad(t *testing.T) {
+	loaded, err := Load(testFiles())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Migration{
+		{Version: 1, Name: "init", Up: "SELECT 1;"},
+		{Version: 2, Name: "create_t", Up: "CREATE TABLE t (a int);", Down: "DROP TABLE t;"},
+		{Version: 3, Name: "no_down", Up: "ALTER TABLE t ADD COLUMN b int;"},
+		{Version: 10, Name: "add_index", Up: "CREATE INDEX idx ON t (a);", Down: "DROP INDEX idx;"},
+	}
+	if len(loaded) != len(want) {
+		t.Fatalf("Load() returned %d migrations, want %d", len(loaded), len(want))
+	}
+
+	for i, migration := range loaded {
+		if migration.Version != want[i].Version || migration.Name != want[i].Name ||
+			migration.Up != want[i].Up || migration.Down != want[i].Down {
+			t.Errorf("migration %d = %+v, want %+v", i, migration, want[i])
+		}
+
+		if len(migration.Checksum) != 64 {
+			t.Errorf("migration %d checksum = %q, want a sha256 hex digest", i, migration.Checksum)
+		}
+	}
+}
+
+func TestLoadShippedMigrations(t *testing.T) {
+	loaded, err := Load(migrations.Files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, migration := range loaded {
+		if migration.Down == "" {
+			t.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+		}
+
+		if i > 0 && migration.Version != loaded[i-1].Version+1 {
+			t.Errorf("migration %d_%s follows version %d", migration.Version, migration.Name, loaded[i-1].Version)
+		}
+	}
+}
+
+func TestLoadChecksum(t *testing.T) {
+	files := testFiles()
+	before, err := Load(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the down file is not part of the checksum
+	files["0002_create_t.down.sql"] = &fstest.MapFile{Data: []byte("DROP TABLE IF EXISTS t;")}
+	sameUp, err := Load(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sameUp[1].Checksum != before[1].Checksum {
+		t.Errorf("changing the down file changed the checksum")
+	}
+
+	files["0002_create_t.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE t (a bigint);")}
+	changedUp, err := Load(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changedUp[1].Checksum == before[1].Checksum {
+		t.Errorf("changing the up file kept the checksum")
+	}
+
+	if changedUp[0].Checksum != before[0].Checksum {
+		t.Errorf("changing one migration changed the checksum of another")
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr string
+	}{
+		{
+			name:    "file name without version",
+			files:   fstest.MapFS{"init.up.sql": {Data: []byte("SELECT 1;")}},
+			wantErr: "invalid migration file name",
+		},
+		{
+			name:    "file name without direction",
+			files:   fstest.MapFS{"0001_init.sql": {Data: []byte("SELECT 1;")}},
+			wantErr: "invalid migration file name",
+		},
+		{
+			name:    "version too large",
+			files:   fstest.MapFS{"99999999999999999999_init.up.sql": {Data: []byte("SELECT 1;")}},
+			wantErr: "invalid migration version",
+		},
+		{
+			name: "two names for one version",
+			files: fstest.MapFS{
+				"0001_init.up.sql":    {Data: []byte("SELECT 1;")},
+				"0001_other.down.sql": {Data: []byte("SELECT 1;")},
+			},
+			wantErr: "has two names",
+		},
+		{
+			name:    "down file without up file",
+			files:   fstest.MapFS{"0001_init.down.sql": {Data: []byte("SELECT 1;")}},
+			wantErr: "has no up file",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Load(tc.files)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Load() error = %v, want it to contain %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckChecksums(t *testing.T) {
+	m := testMigrator(t)
+
+	err := m.checkChecksums(applied(m, 1, 2))
+	if err != nil {
+		t.Fatalf("checkChecksums() error = %v, want nil", err)
+	}
+
+	modified := applied(m, 1, 2)
+	modified[2] = AppliedMigration{Version: 2, Name: "create_t", Checksum: "changed"}
+
+	err = m.checkChecksums(modified)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("checkChecksums() error = %v, want %v", err, ErrChecksumMismatch)
+	}
+
+	if !strings.Contains(err.Error(), "2_create_t") {
+		t.Errorf("checkChecksums() error = %v, want it to name the modified migration", err)
+	}
+
+	// a row of a migration that no longer has files is left alone
+	orphan := applied(m, 1)
+	orphan[7] = AppliedMigration{Version: 7, Name: "removed", Checksum: "whatever"}
+
+	err = m.checkChecksums(orphan)
+	if err != nil {
+		t.Fatalf("checkChecksums() error = %v, want nil", err)
+	}
+}
+
+func TestPending(t *testing.T) {
+	m := testMigrator(t)
+
+	tests := []struct {
+		name    string
+		applied []int64
+		want    []int64
+	}{
+		{name: "nothing applied", want: []int64{1, 2, 3, 10}},
+		{name: "oldest applied", applied: []int64{1, 2}, want: []int64{3, 10}},
+		{name: "gap is filled in order", applied: []int64{1, 10}, want: []int64{2, 3}},
+		{name: "everything applied", applied: []int64{1, 2, 3, 10}, want: []int64{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := versionsOf(m.pending(applied(m, tc.applied...)))
+			if !equalVersions(got, tc.want) {
+				t.Errorf("pending() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRevertible(t *testing.T) {
+	m := testMigrator(t)
+
+	tests := []struct {
+		name    string
+		applied []int64
+		steps   int
+		want    []int64
+		wantErr string
+	}{
+		{name: "one step reverts the newest", applied: []int64{1, 2, 10}, steps: 1, want: []int64{10}},
+		{name: "newest first", applied: []int64{2, 10}, steps: 2, want: []int64{10, 2}},
+		{name: "skips migrations not applied", applied: []int64{1, 2}, steps: 1, want: []int64{2}},
+		{name: "steps beyond the applied ones stop at the oldest", applied: []int64{2, 10}, steps: 5, want: []int64{10, 2}},
+		{name: "zero steps reverts nothing", applied: []int64{2, 10}, steps: 0, want: []int64{}},
+		{name: "nothing applied", steps: 3, want: []int64{}},
+		{name: "missing down file", applied: []int64{1, 2, 3, 10}, steps: 2, wantErr: "3_no_down has no down file"},
+		{name: "missing down file beyond the steps", applied: []int64{1, 2, 3, 10}, steps: 1, want: []int64{10}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := m.revertible(applied(m, tc.applied...), tc.steps)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("revertible() error = %v, want it to contain %q", err, tc.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("revertible() error = %v, want nil", err)
+			}
+
+			if !equalVersions(versionsOf(got), tc.want) {
+				t.Errorf("revertible() = %v, want %v", versionsOf(got), tc.want)
+			}
+		})
+	}
+}
+
+func TestStatuses(t *testing.T) {
+	m := testMigrator(t)
+
+	appliedTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	appliedByVersion := applied(m, 1, 2)
+	first := appliedByVersion[1]
+	first.AppliedTime = appliedTime
+	appliedByVersion[1] = first
+	appliedByVersion[2] = AppliedMigration{Version: 2, Name: "create_t", Checksum: "changed"}
+
+	statuses := m.statuses(appliedByVersion)
+	if len(statuses) != len(m.Migrations) {
+		t.Fatalf("statuses() returned %d rows, want %d", len(statuses), len(m.Migrations))
+	}
+
+	want := []struct {
+		version  int64
+		applied  bool
+		modified bool
+	}{
+		{version: 1, applied: true},
+		{version: 2, applied: true, modified: true},
+		{version: 3},
+		{version: 10},
+	}
+	for i, status := range statuses {
+		if status.Version != want[i].version || status.Applied != want[i].applied || status.Modified != want[i].modified {
+			t.Errorf("status %d = {version: %d, applied: %t, modified: %t}, want %+v",
+				i, status.Version, status.Applied, status.Modified, want[i])
+		}
+	}
+
+	if !statuses[0].AppliedTime.Equal(appliedTime) {
+		t.Errorf("status 0 applied time = %v, want %v", statuses[0].AppliedTime, appliedTime)
+	}
+}
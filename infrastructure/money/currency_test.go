@@ -0,0 +1,88 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExponent(t *testing.T) {
+	tests := []struct {
+		currency string
+		want     int
+		wantErr  error
+	}{
+		{currency: "IDR", want: 2},
+		{currency: "usd", want: 2},
+		{currency: " EUR ", want: 2},
+		{currency: "JPY", want: 0},
+		{currency: "KRW", want: 0},
+		{currency: "KWD", want: 3},
+		{currency: "BHD", want: 3},
+		{currency: "CLF", want: 4},
+		{currency: "XAU", wantErr: ErrUnknownCurrency},
+		{currency: "ABC", wantErr: ErrUnknownCurrency},
+		{currency: "", wantErr: ErrUnknownCurrency},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.currency, func(t *testing.T) {
+			got, err := Exponent(tc.currency)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Exponent(%q) error = %v, want %v", tc.currency, err, tc.wantErr)
+			}
+
+			if got != tc.want {
+				t.Errorf("Exponent(%q) = %d, want %d", tc.currency, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckCurrency(t *testing.T) {
+	tests := []struct {
+		currency string
+		wantErr  error
+	}{
+		{currency: "IDR"},
+		{currency: "USD"},
+		{currency: "JPY"},
+		{currency: "KWD", wantErr: ErrUnsupportedExponent},
+		{currency: "CLF", wantErr: ErrUnsupportedExponent},
+		{currency: "ZZZ", wantErr: ErrUnknownCurrency},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.currency, func(t *testing.T) {
+			err := CheckCurrency(tc.currency)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("CheckCurrency(%q) error = %v, want %v", tc.currency, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestMinorUnit(t *testing.T) {
+	tests := []struct {
+		currency string
+		want     Amount
+		wantErr  error
+	}{
+		{currency: "USD", want: 1},
+		{currency: "JPY", want: 100},
+		{currency: "KWD", wantErr: ErrUnsupportedExponent},
+		{currency: "ZZZ", wantErr: ErrUnknownCurrency},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.currency, func(t *testing.T) {
+			got, err := MinorUnit(tc.currency)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("MinorUnit(%q) error = %v, want %v", tc.currency, err, tc.wantErr)
+			}
+
+			if got != tc.want {
+				t.Errorf("MinorUnit(%q) = %s, want %s", tc.currency, got, tc.want)
+			}
+		})
+	}
+}
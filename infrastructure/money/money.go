@@ -0,0 +1,195 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of decimal places kept by Amount. Amounts are stored in hundredths, a currency
+// with a coarser minor unit keeps whole multiples of it and one with a finer unit is not supported,
+// see MinorUnit.
+const Scale = 2
+
+const DefaultCurrency = "IDR"
+
+var (
+	ErrInvalidAmount  = errors.New("invalid money amount")
+	ErrTooManyDecimal = errors.New("money amount has more than 2 decimal places")
+	ErrOverflow       = errors.New("money amount overflow")
+)
+
+const unit = 100
+
+// Amount is a fixed-point amount in hundredths of the currency unit, so 12.50 is Amount(1250).
+// Arithmetic on amounts is exact; an input with more precision than Scale is rejected instead of
+// being rounded silently. JSON and SQL see it as a plain decimal such as 12.50.
+type Amount int64
+
+func FromMinor(minor int64) Amount {
+	return Amount(minor)
+}
+
+// Parse reads a decimal such as "12", "12.5" or "-0.05". Trailing zeros past Scale are accepted.
+func Parse(value string) (Amount, error) {
+	value = strings.TrimSpace(value)
+
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "-"), "+")
+
+	whole, fraction, _ := strings.Cut(value, ".")
+	if whole == "" && fraction == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	if whole == "" {
+		whole = "0"
+	}
+
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > Scale {
+		return 0, ErrTooManyDecimal
+	}
+
+	fraction += strings.Repeat("0", Scale-len(fraction))
+
+	if !isDigits(whole) || !isDigits(fraction) {
+		return 0, ErrInvalidAmount
+	}
+
+	minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, ErrOverflow
+		}
+
+		return 0, ErrInvalidAmount
+	}
+
+	if negative {
+		minor = -minor
+	}
+
+	return Amount(minor), nil
+}
+
+// FromFloat converts a legacy float amount to the nearest hundredth.
+func FromFloat(value float64) (Amount, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, ErrInvalidAmount
+	}
+
+	return Parse(new(big.Float).SetFloat64(value).Text('f', Scale))
+}
+
+func isDigits(value string) bool {
+	for _, char := range value {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (a Amount) Minor() int64 {
+	return int64(a)
+}
+
+func (a Amount) Float64() float64 {
+	return float64(a) / unit
+}
+
+func (a Amount) String() string {
+	minor := int64(a)
+
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+	}
+
+	// negate in uint64, -minor overflows for the smallest amount
+	abs := uint64(minor)
+	if minor < 0 {
+		abs = -abs
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, abs/unit, abs%unit)
+}
+
+func (a Amount) Add(b Amount) (Amount, error) {
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return 0, ErrOverflow
+	}
+
+	return sum, nil
+}
+
+// Mul multiplies by a quantity, which is exact in minor units.
+func (a Amount) Mul(quantity int64) (Amount, error) {
+	if a == 0 || quantity == 0 {
+		return 0, nil
+	}
+
+	// the division check misses the one product that wraps onto itself
+	if (a == -1 && quantity == math.MinInt64) || (a == math.MinInt64 && quantity == -1) {
+		return 0, ErrOverflow
+	}
+
+	product := a * Amount(quantity)
+	if product/Amount(quantity) != a {
+		return 0, ErrOverflow
+	}
+
+	return product, nil
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a decimal string.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	value := string(data)
+	if value == "null" {
+		return nil
+	}
+
+	amount, err := Parse(strings.Trim(value, `"`))
+	if err != nil {
+		return err
+	}
+
+	*a = amount
+	return nil
+}
+
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+func (a *Amount) Scan(src any) error {
+	var err error
+
+	switch value := src.(type) {
+	case nil:
+		*a = 0
+	case []byte:
+		*a, err = Parse(string(value))
+	case string:
+		*a, err = Parse(value)
+	case int64:
+		*a, err = Amount(value).Mul(unit)
+	case float64:
+		*a, err = FromFloat(value)
+	default:
+		err = fmt.Errorf("%w: cannot scan %T", ErrInvalidAmount, src)
+	}
+
+	return err
+}
@@ -0,0 +1,224 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Amount
+		wantErr error
+	}{
+		{value: "12", want: 1200},
+		{value: "12.5", want: 1250},
+		{value: "12.50", want: 1250},
+		{value: "12.500", want: 1250},
+		{value: ".05", want: 5},
+		{value: "-0.05", want: -5},
+		{value: "+3.10", want: 310},
+		{value: " 7.25 ", want: 725},
+		{value: "0", want: 0},
+		{value: "92233720368547758.07", want: math.MaxInt64},
+		{value: "-92233720368547758.08", wantErr: ErrOverflow},
+		{value: "92233720368547758.08", wantErr: ErrOverflow},
+		{value: "12.345", wantErr: ErrTooManyDecimal},
+		{value: "", wantErr: ErrInvalidAmount},
+		{value: ".", wantErr: ErrInvalidAmount},
+		{value: "1e3", wantErr: ErrInvalidAmount},
+		{value: "12,50", wantErr: ErrInvalidAmount},
+		{value: "--1", wantErr: ErrInvalidAmount},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := Parse(tc.value)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Parse(%q) error = %v, want %v", tc.value, err, tc.wantErr)
+			}
+
+			if got != tc.want {
+				t.Errorf("Parse(%q) = %d, want %d", tc.value, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		amount Amount
+		want   string
+	}{
+		{amount: 0, want: "0.00"},
+		{amount: 5, want: "0.05"},
+		{amount: 1250, want: "12.50"},
+		{amount: -5, want: "-0.05"},
+		{amount: -1250, want: "-12.50"},
+		{amount: math.MaxInt64, want: "92233720368547758.07"},
+		{amount: math.MinInt64, want: "-92233720368547758.08"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.want, func(t *testing.T) {
+			if got := tc.amount.String(); got != tc.want {
+				t.Errorf("String() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFromFloat(t *testing.T) {
+	tests := []struct {
+		value   float64
+		want    Amount
+		wantErr error
+	}{
+		{value: 12.5, want: 1250},
+		{value: 0.1 + 0.2, want: 30},
+		{value: -19.99, want: -1999},
+		{value: 150000.5, want: 15000050},
+		{value: math.NaN(), wantErr: ErrInvalidAmount},
+		{value: math.Inf(1), wantErr: ErrInvalidAmount},
+	}
+
+	for _, tc := range tests {
+		got, err := FromFloat(tc.value)
+		if !errors.Is(err, tc.wantErr) {
+			t.Fatalf("FromFloat(%v) error = %v, want %v", tc.value, err, tc.wantErr)
+		}
+
+		if got != tc.want {
+			t.Errorf("FromFloat(%v) = %s, want %s", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    Amount
+		want    Amount
+		wantErr error
+	}{
+		{name: "positive", a: 1250, b: 5, want: 1255},
+		{name: "negative", a: -1250, b: -5, want: -1255},
+		{name: "mixed signs", a: 1250, b: -1255, want: -5},
+		{name: "largest", a: math.MaxInt64 - 1, b: 1, want: math.MaxInt64},
+		{name: "overflow", a: math.MaxInt64, b: 1, wantErr: ErrOverflow},
+		{name: "underflow", a: math.MinInt64, b: -1, wantErr: ErrOverflow},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.a.Add(tc.b)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Add() error = %v, want %v", err, tc.wantErr)
+			}
+
+			if got != tc.want {
+				t.Errorf("Add() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMul(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   Amount
+		quantity int64
+		want     Amount
+		wantErr  error
+	}{
+		{name: "quantity", amount: 1999, quantity: 3, want: 5997},
+		{name: "negative amount", amount: -1999, quantity: 3, want: -5997},
+		{name: "zero quantity", amount: math.MaxInt64, quantity: 0, want: 0},
+		{name: "zero amount", amount: 0, quantity: math.MaxInt64, want: 0},
+		{name: "overflow", amount: math.MaxInt64/2 + 1, quantity: 2, wantErr: ErrOverflow},
+		{name: "smallest times minus one", amount: math.MinInt64, quantity: -1, wantErr: ErrOverflow},
+		{name: "minus one times smallest", amount: -1, quantity: math.MinInt64, wantErr: ErrOverflow},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.amount.Mul(tc.quantity)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Mul() error = %v, want %v", err, tc.wantErr)
+			}
+
+			if got != tc.want {
+				t.Errorf("Mul() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Amount
+		// output is what the amount marshals back to
+		output string
+	}{
+		{input: `12.5`, want: 1250, output: `12.50`},
+		{input: `"12.5"`, want: 1250, output: `12.50`},
+		{input: `-0.05`, want: -5, output: `-0.05`},
+		{input: `null`, want: 0, output: `0.00`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			var got Amount
+			err := got.UnmarshalJSON([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("UnmarshalJSON() error = %v", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("UnmarshalJSON() = %d, want %d", got, tc.want)
+			}
+
+			output, err := got.MarshalJSON()
+			if err != nil || string(output) != tc.output {
+				t.Errorf("MarshalJSON() = %s, %v, want %s", output, err, tc.output)
+			}
+		})
+	}
+
+	var amount Amount
+	if err := amount.UnmarshalJSON([]byte(`12.345`)); !errors.Is(err, ErrTooManyDecimal) {
+		t.Errorf("UnmarshalJSON(12.345) error = %v, want %v", err, ErrTooManyDecimal)
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     any
+		want    Amount
+		wantErr error
+	}{
+		{name: "numeric text", src: []byte("12.50"), want: 1250},
+		{name: "string", src: "-0.05", want: -5},
+		{name: "integer column", src: int64(12), want: 1200},
+		{name: "float column", src: 12.5, want: 1250},
+		{name: "null", src: nil, want: 0},
+		{name: "unsupported", src: true, wantErr: ErrInvalidAmount},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Amount(99)
+			err := got.Scan(tc.src)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Scan() error = %v, want %v", err, tc.wantErr)
+			}
+
+			if tc.wantErr == nil && got != tc.want {
+				t.Errorf("Scan() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
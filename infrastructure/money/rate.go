@@ -0,0 +1,144 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// RateScale is the number of decimal places a Rate keeps when it is stored or encoded.
+const RateScale = 10
+
+var ErrInvalidRate = errors.New("invalid exchange rate")
+
+// Rate is an exchange rate: how many units of one currency a unit of another is worth. It is an exact
+// decimal, rounding happens only when an Amount is converted with it.
+type Rate struct {
+	value *big.Rat
+}
+
+func OneRate() Rate {
+	return Rate{value: big.NewRat(1, 1)}
+}
+
+// RateFromFraction returns the exact rate num/den, such as 15/100 for a 15% share.
+func RateFromFraction(num, den int64) (Rate, error) {
+	if den == 0 {
+		return Rate{}, ErrInvalidRate
+	}
+
+	return Rate{value: big.NewRat(num, den)}, nil
+}
+
+func ParseRate(value string) (Rate, error) {
+	rat, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok || rat.Sign() <= 0 {
+		return Rate{}, ErrInvalidRate
+	}
+
+	// keep only what can be stored, so a rate reads back exactly as it was used
+	rat, _ = new(big.Rat).SetString(rat.FloatString(RateScale))
+	if rat.Sign() <= 0 {
+		return Rate{}, ErrInvalidRate
+	}
+
+	return Rate{value: rat}, nil
+}
+
+func (r Rate) IsZero() bool {
+	return r.value == nil
+}
+
+func (r Rate) rat() *big.Rat {
+	if r.value == nil {
+		return big.NewRat(1, 1)
+	}
+
+	return r.value
+}
+
+// Inverse returns the exact rate of the opposite direction. It is only rounded once stored or encoded.
+func (r Rate) Inverse() Rate {
+	return Rate{value: new(big.Rat).Inv(r.rat())}
+}
+
+func (r Rate) String() string {
+	value := strings.TrimRight(r.rat().FloatString(RateScale), "0")
+	return strings.TrimSuffix(value, ".")
+}
+
+// Convert multiplies the amount by the rate and rounds the result half away from zero to a hundredth.
+func (a Amount) Convert(rate Rate) (Amount, error) {
+	return a.convert(rate, 1)
+}
+
+// ConvertTo multiplies the amount by the rate and rounds the result half away from zero to the minor
+// unit of currency, so 150.50 JPY comes out as 151.
+func (a Amount) ConvertTo(rate Rate, currency string) (Amount, error) {
+	unit, err := MinorUnit(currency)
+	if err != nil {
+		return 0, err
+	}
+
+	return a.convert(rate, unit)
+}
+
+// convert rounds to a whole multiple of unit hundredths.
+func (a Amount) convert(rate Rate, unit Amount) (Amount, error) {
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(int64(a)), rate.rat())
+	product.Quo(product, new(big.Rat).SetInt64(int64(unit)))
+
+	// QuoRem truncates toward zero, the remainder decides whether to round away from it
+	quotient, remainder := new(big.Int).QuoRem(product.Num(), product.Denom(), new(big.Int))
+	twiceRemainder := new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2))
+	if twiceRemainder.Cmp(product.Denom()) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(product.Sign())))
+	}
+
+	quotient.Mul(quotient, big.NewInt(int64(unit)))
+	if !quotient.IsInt64() {
+		return 0, ErrOverflow
+	}
+
+	return Amount(quotient.Int64()), nil
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + r.String() + `"`), nil
+}
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if value == "null" {
+		return nil
+	}
+
+	rate, err := ParseRate(value)
+	if err != nil {
+		return err
+	}
+
+	*r = rate
+	return nil
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+func (r *Rate) Scan(src any) error {
+	var err error
+
+	switch value := src.(type) {
+	case []byte:
+		*r, err = ParseRate(string(value))
+	case string:
+		*r, err = ParseRate(value)
+	default:
+		err = fmt.Errorf("%w: cannot scan %T", ErrInvalidRate, src)
+	}
+
+	return err
+}
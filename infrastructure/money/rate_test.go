@@ -0,0 +1,163 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func mustRate(t *testing.T, num, den int64) Rate {
+	t.Helper()
+
+	rate, err := RateFromFraction(num, den)
+	if err != nil {
+		t.Fatalf("RateFromFraction(%d, %d) error = %v", num, den, err)
+	}
+
+	return rate
+}
+
+func TestRateFromFraction(t *testing.T) {
+	rate, err := RateFromFraction(15, 100)
+	if err != nil || rate.String() != "0.15" {
+		t.Errorf("RateFromFraction(15, 100) = %s, %v, want 0.15", rate, err)
+	}
+
+	_, err = RateFromFraction(1, 0)
+	if !errors.Is(err, ErrInvalidRate) {
+		t.Errorf("RateFromFraction(1, 0) error = %v, want %v", err, ErrInvalidRate)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   Amount
+		num, den int64
+		want     Amount
+		wantErr  error
+	}{
+		{name: "exact", amount: 1000, num: 3, den: 2, want: 1500},
+		{name: "below half a cent rounds down", amount: 149, num: 1, den: 100, want: 1},
+		{name: "half a cent rounds up", amount: 150, num: 1, den: 100, want: 2},
+		{name: "above half a cent rounds up", amount: 151, num: 1, den: 100, want: 2},
+		{name: "half of one cent", amount: 1, num: 1, den: 2, want: 1},
+		{name: "third of one cent", amount: 1, num: 1, den: 3, want: 0},
+		{name: "two thirds of one cent", amount: 1, num: 2, den: 3, want: 1},
+		{name: "negative below half a cent", amount: -149, num: 1, den: 100, want: -1},
+		{name: "negative half a cent rounds away from zero", amount: -150, num: 1, den: 100, want: -2},
+		{name: "negative half of one cent", amount: -1, num: 1, den: 2, want: -1},
+		{name: "negative rate", amount: 150, num: -1, den: 100, want: -2},
+		{name: "inclusive tax share", amount: 11100, num: 1100, den: 11100, want: 1100},
+		{name: "exclusive tax", amount: 10000, num: 725, den: 10000, want: 725},
+		{name: "zero", amount: 0, num: 16250, den: 1, want: 0},
+		{name: "largest that fits", amount: math.MaxInt64, num: 1, den: 1, want: math.MaxInt64},
+		{name: "overflow", amount: math.MaxInt64, num: 2, den: 1, wantErr: ErrOverflow},
+		{name: "slightly above one overflows", amount: math.MaxInt64, num: math.MaxInt64, den: math.MaxInt64 - 1, wantErr: ErrOverflow},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.amount.Convert(mustRate(t, tc.num, tc.den))
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Convert() error = %v, want %v", err, tc.wantErr)
+			}
+
+			if got != tc.want {
+				t.Errorf("Convert() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConvertTo(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   Amount
+		num, den int64
+		currency string
+		want     Amount
+		wantErr  error
+	}{
+		{name: "hundredths", amount: 1000000, num: 1, den: 16250, currency: "USD", want: 62},
+		{name: "whole yen", amount: 1625000, num: 1, den: 108, currency: "JPY", want: 15000},
+		{name: "yen below half rounds down", amount: 14949, num: 1, den: 1, currency: "JPY", want: 14900},
+		{name: "yen half rounds up", amount: 15050, num: 1, den: 1, currency: "JPY", want: 15100},
+		{name: "negative yen half rounds away from zero", amount: -15050, num: 1, den: 1, currency: "JPY", want: -15100},
+		{name: "below half a yen", amount: 49, num: 1, den: 1, currency: "JPY", want: 0},
+		{name: "yen tax share", amount: 100000, num: 725, den: 10000, currency: "JPY", want: 7300},
+		{name: "currency is case insensitive", amount: 15050, num: 1, den: 1, currency: "jpy", want: 15100},
+		{name: "three decimal currency", amount: 1000, num: 1, den: 1, currency: "KWD", wantErr: ErrUnsupportedExponent},
+		{name: "unknown currency", amount: 1000, num: 1, den: 1, currency: "ZZZ", wantErr: ErrUnknownCurrency},
+		{name: "overflow", amount: math.MaxInt64, num: 2, den: 1, currency: "JPY", wantErr: ErrOverflow},
+		{name: "largest yen", amount: math.MaxInt64, num: 1, den: 1, currency: "JPY", want: math.MaxInt64 - 7},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.amount.ConvertTo(mustRate(t, tc.num, tc.den), tc.currency)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ConvertTo() error = %v, want %v", err, tc.wantErr)
+			}
+
+			if got != tc.want {
+				t.Errorf("ConvertTo() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+// A zero Rate is treated as one, so an order without a stored rate converts to itself.
+func TestConvertZeroRate(t *testing.T) {
+	got, err := Amount(1250).Convert(Rate{})
+	if err != nil || got != 1250 {
+		t.Errorf("Convert(zero rate) = %s, %v, want 12.50", got, err)
+	}
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	rate, err := ParseRate("16250")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 10.00 USD at 16250 IDR each, and back
+	base, err := Amount(1000).Convert(rate)
+	if err != nil || base != 16250000 {
+		t.Fatalf("Convert() = %s, %v, want 162500.00", base, err)
+	}
+
+	charged, err := base.Convert(rate.Inverse())
+	if err != nil || charged != 1000 {
+		t.Errorf("Convert(inverse) = %s, %v, want 10.00", charged, err)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr error
+	}{
+		{value: "16250", want: "16250"},
+		{value: " 0.5 ", want: "0.5"},
+		{value: "1/3", want: "0.3333333333"},
+		{value: "0.00000000001", wantErr: ErrInvalidRate},
+		{value: "0", wantErr: ErrInvalidRate},
+		{value: "-1", wantErr: ErrInvalidRate},
+		{value: "abc", wantErr: ErrInvalidRate},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := ParseRate(tc.value)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ParseRate(%q) error = %v, want %v", tc.value, err, tc.wantErr)
+			}
+
+			if tc.wantErr == nil && got.String() != tc.want {
+				t.Errorf("ParseRate(%q) = %s, want %s", tc.value, got, tc.want)
+			}
+		})
+	}
+}
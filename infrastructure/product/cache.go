@@ -0,0 +1,142 @@
+package product
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"order/models"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	CacheStoreMemory = "memory"
+	CacheStoreRedis  = "redis"
+)
+
+// Cache keeps product info for a short time so repeated checkouts do not hit product service.
+type Cache interface {
+	GetMany(ctx context.Context, productIDs []int64) (map[int64]models.Product, error)
+	SetMany(ctx context.Context, products []models.Product, ttl time.Duration) error
+}
+
+type memoryEntry struct {
+	product  models.Product
+	expireAt time.Time
+}
+
+type MemoryCache struct {
+	mu      sync.RWMutex
+	entries map[int64]memoryEntry
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		entries: map[int64]memoryEntry{},
+	}
+}
+
+func (c *MemoryCache) GetMany(ctx context.Context, productIDs []int64) (map[int64]models.Product, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := time.Now()
+	products := make(map[int64]models.Product, len(productIDs))
+	for _, id := range productIDs {
+		entry, isExist := c.entries[id]
+		if isExist && now.Before(entry.expireAt) {
+			products[id] = entry.product
+		}
+	}
+
+	return products, nil
+}
+
+func (c *MemoryCache) SetMany(ctx context.Context, products []models.Product, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for id, entry := range c.entries {
+		if now.After(entry.expireAt) {
+			delete(c.entries, id)
+		}
+	}
+
+	for _, product := range products {
+		c.entries[product.ID] = memoryEntry{
+			product:  product,
+			expireAt: now.Add(ttl),
+		}
+	}
+
+	return nil
+}
+
+type RedisCache struct {
+	Client *redis.Client
+}
+
+func NewRedisCache(client *redis.Client) *RedisCache {
+	return &RedisCache{
+		Client: client,
+	}
+}
+
+func cacheKey(productID int64) string {
+	return fmt.Sprintf("product:info:%d", productID)
+}
+
+func (c *RedisCache) GetMany(ctx context.Context, productIDs []int64) (map[int64]models.Product, error) {
+	products := make(map[int64]models.Product, len(productIDs))
+	if len(productIDs) == 0 {
+		return products, nil
+	}
+
+	keys := make([]string, len(productIDs))
+	for index, id := range productIDs {
+		keys[index] = cacheKey(id)
+	}
+
+	values, err := c.Client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		var product models.Product
+		if err := json.Unmarshal([]byte(raw), &product); err != nil {
+			continue
+		}
+
+		products[product.ID] = product
+	}
+
+	return products, nil
+}
+
+func (c *RedisCache) SetMany(ctx context.Context, products []models.Product, ttl time.Duration) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	pipe := c.Client.Pipeline()
+	for _, product := range products {
+		value, err := json.Marshal(product)
+		if err != nil {
+			return err
+		}
+
+		pipe.Set(ctx, cacheKey(product.ID), value, ttl)
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
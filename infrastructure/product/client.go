@@ -0,0 +1,404 @@
+package product
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"order/config"
+	"order/infrastructure/circuitbreaker"
+	"order/models"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout         = 2 * time.Second
+	defaultMaxConcurrency  = 10
+	defaultCacheTTL        = 5 * time.Second
+	defaultMaxRetries      = 2
+	defaultRetryBackoff    = 50 * time.Millisecond
+	defaultMaxRetryBackoff = 500 * time.Millisecond
+
+	defaultBreakerFailureRate = 0.5
+	defaultBreakerMinRequests = 10
+	defaultBreakerWindow      = 10 * time.Second
+	defaultBreakerOpenTimeout = 5 * time.Second
+
+	// defaultReservationTTL outlives the default payment window, so the expiry worker releases an
+	// unpaid order's stock before product service gives up on it
+	defaultReservationTTL = 35 * time.Minute
+)
+
+// Client talks to product service. Lookups go through a short-lived cache and, for many products,
+// either the batch endpoint or a bounded pool of concurrent single lookups. Failing requests are
+// retried and a circuit breaker fails fast while product service is down.
+type Client struct {
+	Host            string
+	HTTPClient      *http.Client
+	MaxConcurrency  int
+	BatchEnabled    bool
+	Cache           Cache
+	CacheTTL        time.Duration
+	MaxRetries      int
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	Breaker         *circuitbreaker.Breaker
+
+	// ReservationEnabled makes checkout hold stock with ReserveStock instead of relying on stock.update.
+	ReservationEnabled bool
+	ReservationTTL     time.Duration
+}
+
+func NewClient(cfg config.ProductConfig, cache Cache) *Client {
+	client := &Client{
+		Host:            strings.TrimRight(cfg.Host, "/"),
+		HTTPClient:      &http.Client{Timeout: cfg.Timeout},
+		MaxConcurrency:  cfg.MaxConcurrency,
+		BatchEnabled:    cfg.BatchEnabled,
+		Cache:           cache,
+		CacheTTL:        cfg.CacheTTL,
+		MaxRetries:      cfg.MaxRetries,
+		RetryBackoff:    cfg.RetryBackoff,
+		MaxRetryBackoff: cfg.MaxRetryBackoff,
+
+		ReservationEnabled: cfg.ReservationEnabled,
+		ReservationTTL:     cfg.ReservationTTL,
+	}
+
+	if client.HTTPClient.Timeout <= 0 {
+		client.HTTPClient.Timeout = defaultTimeout
+	}
+
+	if client.MaxConcurrency <= 0 {
+		client.MaxConcurrency = defaultMaxConcurrency
+	}
+
+	if client.CacheTTL <= 0 {
+		client.CacheTTL = defaultCacheTTL
+	}
+
+	// zero turns retries off, only a negative value falls back to the default
+	if client.MaxRetries < 0 {
+		client.MaxRetries = defaultMaxRetries
+	}
+
+	if client.RetryBackoff <= 0 {
+		client.RetryBackoff = defaultRetryBackoff
+	}
+
+	if client.MaxRetryBackoff <= 0 {
+		client.MaxRetryBackoff = defaultMaxRetryBackoff
+	}
+
+	if client.ReservationTTL <= 0 {
+		client.ReservationTTL = defaultReservationTTL
+	}
+
+	breakerCfg := circuitbreaker.Config{
+		FailureRate: cfg.BreakerFailureRate,
+		MinRequests: cfg.BreakerMinRequests,
+		Window:      cfg.BreakerWindow,
+		OpenTimeout: cfg.BreakerOpenTimeout,
+	}
+
+	if breakerCfg.FailureRate <= 0 || breakerCfg.FailureRate > 1 {
+		breakerCfg.FailureRate = defaultBreakerFailureRate
+	}
+
+	if breakerCfg.MinRequests <= 0 {
+		breakerCfg.MinRequests = defaultBreakerMinRequests
+	}
+
+	if breakerCfg.Window <= 0 {
+		breakerCfg.Window = defaultBreakerWindow
+	}
+
+	if breakerCfg.OpenTimeout <= 0 {
+		breakerCfg.OpenTimeout = defaultBreakerOpenTimeout
+	}
+
+	client.Breaker = circuitbreaker.New(breakerCfg)
+
+	return client
+}
+
+func (c *Client) GetProduct(ctx context.Context, productID int64) (models.Product, error) {
+	products, err := c.GetProducts(ctx, []int64{productID})
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	return products[productID], nil
+}
+
+// GetProducts returns every requested product keyed by id, reading through the cache.
+func (c *Client) GetProducts(ctx context.Context, productIDs []int64) (map[int64]models.Product, error) {
+	products := make(map[int64]models.Product, len(productIDs))
+
+	if c.Cache != nil {
+		cached, err := c.Cache.GetMany(ctx, productIDs)
+		if err == nil {
+			for id, product := range cached {
+				products[id] = product
+			}
+		}
+	}
+
+	missing := make([]int64, 0, len(productIDs))
+	for _, id := range productIDs {
+		if _, isExist := products[id]; !isExist {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) == 0 {
+		return products, nil
+	}
+
+	var fetched []models.Product
+	var err error
+	if c.BatchEnabled {
+		fetched, err = c.fetchBatch(ctx, missing)
+	} else {
+		fetched, err = c.fetchConcurrently(ctx, missing)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, product := range fetched {
+		products[product.ID] = product
+	}
+
+	if c.Cache != nil {
+		// a cache write failure only costs a later round-trip
+		_ = c.Cache.SetMany(ctx, fetched, c.CacheTTL)
+	}
+
+	return products, nil
+}
+
+func (c *Client) fetchConcurrently(ctx context.Context, productIDs []int64) ([]models.Product, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]models.Product, len(productIDs))
+	semaphore := make(chan struct{}, c.MaxConcurrency)
+
+	// the first failure cancels the lookups still running, its error is the one reported
+	var firstErr error
+	var once sync.Once
+
+	var wg sync.WaitGroup
+	for index, productID := range productIDs {
+		wg.Add(1)
+		semaphore <- struct{}{}
+
+		go func(index int, productID int64) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			product, err := c.fetchOne(ctx, productID)
+			if err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("product %d: %w", productID, err)
+					cancel()
+				})
+
+				return
+			}
+
+			results[index] = product
+		}(index, productID)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return results, nil
+}
+
+func (c *Client) fetchOne(ctx context.Context, productID int64) (models.Product, error) {
+	var response models.GetProductInfo
+
+	url := fmt.Sprintf("%s/v1/product/%d", c.Host, productID)
+	err := c.sendJSON(ctx, http.MethodGet, url, nil, &response)
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	return response.Product, nil
+}
+
+func (c *Client) fetchBatch(ctx context.Context, productIDs []int64) ([]models.Product, error) {
+	var response models.GetProductsInfo
+
+	ids := make([]string, len(productIDs))
+	for index, id := range productIDs {
+		ids[index] = strconv.FormatInt(id, 10)
+	}
+
+	url := fmt.Sprintf("%s/v1/products?ids=%s", c.Host, strings.Join(ids, ","))
+	err := c.sendJSON(ctx, http.MethodGet, url, nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[int64]bool, len(response.Products))
+	for _, product := range response.Products {
+		found[product.ID] = true
+	}
+
+	for _, id := range productIDs {
+		if !found[id] {
+			return nil, fmt.Errorf("product %d: %w", id, ErrProductNotFound)
+		}
+	}
+
+	return response.Products, nil
+}
+
+// ReserveStock holds the quantities of every item for ReservationTTL, all or nothing. Product service
+// deduplicates on the reference id, so a retried request never holds the stock twice.
+func (c *Client) ReserveStock(ctx context.Context, referenceID string, items []models.ProductItem) (models.StockReservation, error) {
+	var response models.GetStockReservation
+
+	url := fmt.Sprintf("%s/v1/product/reservations", c.Host)
+	err := c.sendJSON(ctx, http.MethodPost, url, models.StockReservationRequest{
+		ReferenceID: referenceID,
+		Items:       items,
+		TTLSeconds:  int64(c.ReservationTTL / time.Second),
+	}, &response)
+	if err != nil {
+		return models.StockReservation{}, err
+	}
+
+	return response.StockReservation, nil
+}
+
+// ReleaseReservation gives the held stock back. A reservation product service no longer knows, because
+// it expired or was released already, is not an error.
+func (c *Client) ReleaseReservation(ctx context.Context, reservationID string) error {
+	url := fmt.Sprintf("%s/v1/product/reservations/%s", c.Host, reservationID)
+	err := c.sendJSON(ctx, http.MethodDelete, url, nil, nil)
+	if err != nil && !errors.Is(err, ErrProductNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// sendJSON sends body, when there is one, and decodes the response into target. Transport errors, 5xx
+// and 429 responses are retried with exponential backoff and full jitter, every attempt going through
+// the circuit breaker, so only idempotent requests may be sent.
+func (c *Client) sendJSON(ctx context.Context, method string, url string, body any, target any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	var lastErr error
+
+	for attempt := 0; attempt <= c.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.retryBackoff(attempt)):
+			}
+		}
+
+		if err := c.Breaker.Allow(); err != nil {
+			return fmt.Errorf("%w: %v", ErrProductServiceUnavailable, err)
+		}
+
+		retryable, err := c.doJSON(ctx, method, url, payload, target)
+		switch {
+		case err == nil:
+			c.Breaker.Success()
+			return nil
+		case ctx.Err() != nil:
+			c.Breaker.Ignore()
+			return ctx.Err()
+		case !retryable:
+			c.Breaker.Success()
+			return err
+		}
+
+		c.Breaker.Failure()
+		lastErr = err
+	}
+
+	return fmt.Errorf("%w: %v", ErrProductServiceUnavailable, lastErr)
+}
+
+// doJSON performs a single request and reports whether its failure is worth retrying.
+func (c *Client) doJSON(ctx context.Context, method string, url string, payload []byte, target any) (bool, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return false, err
+	}
+
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusOK || res.StatusCode == http.StatusCreated || res.StatusCode == http.StatusNoContent:
+	case res.StatusCode == http.StatusNotFound:
+		return false, ErrProductNotFound
+	case res.StatusCode == http.StatusConflict:
+		return false, ErrInsufficientStock
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError:
+		return true, fmt.Errorf("Invalid response - %s returned %d", url, res.StatusCode)
+	default:
+		return false, fmt.Errorf("Invalid response - %s returned %d", url, res.StatusCode)
+	}
+
+	if target == nil || res.StatusCode == http.StatusNoContent {
+		return false, nil
+	}
+
+	err = json.NewDecoder(res.Body).Decode(target)
+	if err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
+
+func (c *Client) retryBackoff(attempt int) time.Duration {
+	backoff := c.RetryBackoff << (attempt - 1)
+	if backoff <= 0 || backoff > c.MaxRetryBackoff {
+		backoff = c.MaxRetryBackoff
+	}
+
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
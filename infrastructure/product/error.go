@@ -0,0 +1,9 @@
+package product
+
+import "errors"
+
+var (
+	ErrProductNotFound           = errors.New("product not found")
+	ErrProductServiceUnavailable = errors.New("product service unavailable")
+	ErrInsufficientStock         = errors.New("insufficient stock")
+)
@@ -0,0 +1,187 @@
+package promotion
+
+import (
+	"math/big"
+	"order/infrastructure/constant"
+	"order/infrastructure/money"
+	"order/models"
+	"time"
+)
+
+// Available reports why a coupon cannot be redeemed by a user who already used it usage times, nil
+// when it can.
+func Available(coupon models.Coupon, usage int, now time.Time) error {
+	if coupon.StartTime != nil && now.Before(*coupon.StartTime) {
+		return unavailable(coupon, "is not active yet")
+	}
+
+	if coupon.ExpireTime != nil && !now.Before(*coupon.ExpireTime) {
+		return unavailable(coupon, "has expired")
+	}
+
+	if coupon.MaxUses > 0 && coupon.UsedCount >= coupon.MaxUses {
+		return unavailable(coupon, "has been fully redeemed")
+	}
+
+	if coupon.MaxUsesPerUser > 0 && usage >= coupon.MaxUsesPerUser {
+		return unavailable(coupon, "has reached its usage limit for this user")
+	}
+
+	return nil
+}
+
+// Apply prices the items with the coupons in the order they were given. Every discount is taken
+// from what is left after the previous ones, so the total never drops below zero. Coupon amounts are
+// in the base currency and converted with toCharged to currency, the one the items are priced in;
+// usage is the user's past redemptions per coupon.
+func Apply(coupons []models.Coupon, items []models.OrderItem, usage map[int64]int, toCharged money.Rate, currency string, now time.Time) (models.DiscountBreakdown, error) {
+	var breakdown models.DiscountBreakdown
+
+	for _, item := range items {
+		subtotal, err := breakdown.Subtotal.Add(item.LineTotal)
+		if err != nil {
+			return models.DiscountBreakdown{}, err
+		}
+
+		breakdown.Subtotal = subtotal
+	}
+
+	remaining := breakdown.Subtotal
+
+	for _, coupon := range coupons {
+		err := Available(coupon, usage[coupon.ID], now)
+		if err != nil {
+			return models.DiscountBreakdown{}, err
+		}
+
+		minSpend, err := coupon.MinSpend.ConvertTo(toCharged, currency)
+		if err != nil {
+			return models.DiscountBreakdown{}, err
+		}
+
+		if breakdown.Subtotal < minSpend {
+			return models.DiscountBreakdown{}, unavailable(coupon, "requires a minimum spend of "+minSpend.String())
+		}
+
+		discount, err := discountOf(coupon, items, remaining, toCharged, currency)
+		if err != nil {
+			return models.DiscountBreakdown{}, err
+		}
+
+		if discount <= 0 {
+			return models.DiscountBreakdown{}, unavailable(coupon, "does not apply to this cart")
+		}
+
+		if discount > remaining {
+			discount = remaining
+		}
+
+		remaining -= discount
+		breakdown.DiscountAmount += discount
+		breakdown.Redemptions = append(breakdown.Redemptions, models.CouponRedemption{
+			CouponID:       coupon.ID,
+			Code:           coupon.Code,
+			DiscountAmount: discount,
+			CreateTime:     now,
+		})
+		breakdown.Discounts = append(breakdown.Discounts, models.AppliedDiscount{
+			Code:   coupon.Code,
+			Amount: discount,
+		})
+	}
+
+	breakdown.Total = remaining
+
+	return breakdown, nil
+}
+
+// Allocate spreads an order discount over the items in proportion to their line totals. Shares are
+// whole multiples of unit, the minor unit of the order currency, rounded down; what is left over goes
+// a unit at a time to the first lines that still have room, so the shares add up to the discount
+// exactly and no line is discounted below zero.
+func Allocate(discount money.Amount, items []models.OrderItem, unit money.Amount) []money.Amount {
+	shares := make([]money.Amount, len(items))
+
+	var subtotal money.Amount
+	for _, item := range items {
+		subtotal += item.LineTotal
+	}
+
+	if discount <= 0 || subtotal <= 0 {
+		return shares
+	}
+
+	if discount > subtotal {
+		discount = subtotal
+	}
+
+	if unit <= 0 {
+		unit = 1
+	}
+
+	units := big.NewInt(discount.Minor() / unit.Minor())
+	left := discount
+	for index, item := range items {
+		share := new(big.Int).Mul(units, big.NewInt(item.LineTotal.Minor()))
+		share.Quo(share, big.NewInt(subtotal.Minor()))
+
+		shares[index] = money.FromMinor(share.Int64() * unit.Minor())
+		left -= shares[index]
+	}
+
+	for index := 0; left > 0; index = (index + 1) % len(items) {
+		room := items[index].LineTotal - shares[index]
+		if room > 0 {
+			share := min(unit, room, left)
+			shares[index] += share
+			left -= share
+		}
+	}
+
+	return shares
+}
+
+// discountOf rounds percentage discounts half away from zero to the minor unit of the order currency.
+func discountOf(coupon models.Coupon, items []models.OrderItem, remaining money.Amount, toCharged money.Rate, currency string) (money.Amount, error) {
+	switch coupon.Type {
+	case constant.CouponTypePercentage:
+		// DiscountValue holds the percentage, 12.50 is 12.5%
+		if coupon.DiscountValue <= 0 || coupon.DiscountValue > 100*100 {
+			return 0, unavailable(coupon, "has an invalid percentage")
+		}
+
+		share, err := money.RateFromFraction(coupon.DiscountValue.Minor(), 100*100)
+		if err != nil {
+			return 0, err
+		}
+
+		return remaining.ConvertTo(share, currency)
+	case constant.CouponTypeFixed:
+		return coupon.DiscountValue.ConvertTo(toCharged, currency)
+	case constant.CouponTypeBuyXGetY:
+		if coupon.BuyQty <= 0 || coupon.GetQty <= 0 {
+			return 0, unavailable(coupon, "has an invalid buy/get quantity")
+		}
+
+		for _, item := range items {
+			if item.ProductID != coupon.BuyProductID {
+				continue
+			}
+
+			// every buy+get units of the product, get of them are free
+			free := item.Quantity / (coupon.BuyQty + coupon.GetQty) * coupon.GetQty
+			return item.UnitPrice.Mul(int64(free))
+		}
+
+		return 0, nil
+	default:
+		return 0, unavailable(coupon, "has an unknown type")
+	}
+}
+
+func unavailable(coupon models.Coupon, reason string) error {
+	return &constant.CouponUnavailableError{
+		Code:   coupon.Code,
+		Reason: reason,
+	}
+}
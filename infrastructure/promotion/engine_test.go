@@ -0,0 +1,291 @@
+package promotion
+
+import (
+	"errors"
+	"math/rand"
+	"order/infrastructure/constant"
+	"order/infrastructure/money"
+	"order/models"
+	"testing"
+	"time"
+)
+
+func lines(totals ...money.Amount) []models.OrderItem {
+	items := make([]models.OrderItem, len(totals))
+	for index, total := range totals {
+		items[index] = models.OrderItem{
+			ProductID: int64(index + 1),
+			UnitPrice: total,
+			Quantity:  1,
+			LineTotal: total,
+		}
+	}
+
+	return items
+}
+
+func sum(amounts []money.Amount) money.Amount {
+	var total money.Amount
+	for _, amount := range amounts {
+		total += amount
+	}
+
+	return total
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name     string
+		discount money.Amount
+		items    []models.OrderItem
+		unit     money.Amount
+		want     []money.Amount
+	}{
+		{name: "even split", discount: 1000, items: lines(5000, 5000), want: []money.Amount{500, 500}},
+		{name: "proportional", discount: 1000, items: lines(7500, 2500), want: []money.Amount{750, 250}},
+		{name: "leftover cent goes to the first line", discount: 100, items: lines(1000, 1000, 1000), want: []money.Amount{34, 33, 33}},
+		{name: "leftover cents spread over lines", discount: 200, items: lines(1000, 1000, 1000), want: []money.Amount{67, 67, 66}},
+		{name: "one cent", discount: 1, items: lines(999, 1), want: []money.Amount{1, 0}},
+		{name: "full line skipped for leftover", discount: 1001, items: lines(1, 1000, 1000), want: []money.Amount{1, 500, 500}},
+		{name: "whole subtotal", discount: 3333, items: lines(1111, 1111, 1111), want: []money.Amount{1111, 1111, 1111}},
+		{name: "capped at subtotal", discount: 5000, items: lines(1000, 2000), want: []money.Amount{1000, 2000}},
+		{name: "no discount", discount: 0, items: lines(1000, 2000), want: []money.Amount{0, 0}},
+		{name: "free lines", discount: 100, items: lines(0, 0), want: []money.Amount{0, 0}},
+		{name: "no lines", discount: 100, items: nil, want: []money.Amount{}},
+		{name: "whole yen", discount: 10000, items: lines(100000, 100000, 100000), unit: 100, want: []money.Amount{3400, 3300, 3300}},
+		{name: "yen leftover spread over lines", discount: 20000, items: lines(100000, 100000, 100000), unit: 100, want: []money.Amount{6700, 6700, 6600}},
+		{name: "fraction of a yen goes to the first line", discount: 10050, items: lines(10000, 10000), unit: 100, want: []money.Amount{5050, 5000}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			unit := tc.unit
+			if unit == 0 {
+				unit = 1
+			}
+
+			got := Allocate(tc.discount, tc.items, unit)
+			if len(got) != len(tc.want) {
+				t.Fatalf("Allocate() = %v, want %v", got, tc.want)
+			}
+
+			for index := range got {
+				if got[index] != tc.want[index] {
+					t.Fatalf("Allocate() = %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+// Whatever the line totals, the shares add up to the discount exactly, stay within their line and are
+// whole minor units of the currency.
+func TestAllocateSumsToDiscount(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	for _, unit := range []money.Amount{1, 100} {
+		for run := 0; run < 1000; run++ {
+			totals := make([]money.Amount, 1+random.Intn(8))
+			for index := range totals {
+				totals[index] = money.FromMinor(random.Int63n(1000)) * unit
+			}
+
+			items := lines(totals...)
+			subtotal := sum(totals)
+			discount := money.FromMinor(random.Int63n(subtotal.Minor()/unit.Minor()+1)) * unit
+
+			shares := Allocate(discount, items, unit)
+			if got := sum(shares); got != discount {
+				t.Fatalf("Allocate(%s, %v, %s) sums to %s", discount, totals, unit, got)
+			}
+
+			for index, share := range shares {
+				if share < 0 || share > totals[index] || share%unit != 0 {
+					t.Fatalf("Allocate(%s, %v, %s) gave line %d a share of %s", discount, totals, unit, index, share)
+				}
+			}
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	percentage := func(code string, value money.Amount) models.Coupon {
+		return models.Coupon{ID: int64(len(code)), Code: code, Type: constant.CouponTypePercentage, DiscountValue: value}
+	}
+	fixed := func(code string, value money.Amount) models.Coupon {
+		return models.Coupon{ID: int64(len(code)), Code: code, Type: constant.CouponTypeFixed, DiscountValue: value}
+	}
+	halfRate, _ := money.RateFromFraction(1, 2)
+
+	tests := []struct {
+		name      string
+		coupons   []models.Coupon
+		items     []models.OrderItem
+		toCharged money.Rate
+		currency  string
+		want      []money.Amount
+		wantTotal money.Amount
+	}{
+		{
+			name:      "no coupons",
+			items:     lines(1000, 2000),
+			wantTotal: 3000,
+		},
+		{
+			name:      "percentage",
+			coupons:   []models.Coupon{percentage("TEN", 1000)},
+			items:     lines(1000, 2000),
+			want:      []money.Amount{300},
+			wantTotal: 2700,
+		},
+		{
+			name:      "percentage rounds half away from zero",
+			coupons:   []models.Coupon{percentage("HALF", 50)},
+			items:     lines(100),
+			want:      []money.Amount{1},
+			wantTotal: 99,
+		},
+		{
+			name:      "fixed in base currency",
+			coupons:   []models.Coupon{fixed("FIVE", 500)},
+			items:     lines(3000),
+			toCharged: halfRate,
+			want:      []money.Amount{250},
+			wantTotal: 2750,
+		},
+		{
+			name:      "stacked on what is left",
+			coupons:   []models.Coupon{fixed("FIVE", 1000), percentage("TEN", 1000)},
+			items:     lines(3000),
+			want:      []money.Amount{1000, 200},
+			wantTotal: 1800,
+		},
+		{
+			name:      "never below zero",
+			coupons:   []models.Coupon{fixed("BIG", 2500), fixed("MORE", 2500)},
+			items:     lines(3000),
+			want:      []money.Amount{2500, 500},
+			wantTotal: 0,
+		},
+		{
+			name: "buy two get one",
+			coupons: []models.Coupon{{
+				ID: 1, Code: "B2G1", Type: constant.CouponTypeBuyXGetY, BuyProductID: 7, BuyQty: 2, GetQty: 1,
+			}},
+			items: []models.OrderItem{
+				{ProductID: 7, UnitPrice: 400, Quantity: 7, LineTotal: 2800},
+				{ProductID: 8, UnitPrice: 1000, Quantity: 1, LineTotal: 1000},
+			},
+			want:      []money.Amount{800},
+			wantTotal: 3000,
+		},
+		{
+			name:      "percentage rounded to whole yen",
+			coupons:   []models.Coupon{percentage("TEN", 1000)},
+			items:     lines(123400),
+			currency:  "JPY",
+			want:      []money.Amount{12300},
+			wantTotal: 111100,
+		},
+		{
+			name:      "fixed converted to whole yen",
+			coupons:   []models.Coupon{fixed("FIVE", 500)},
+			items:     lines(100000),
+			toCharged: halfRate,
+			currency:  "JPY",
+			want:      []money.Amount{300},
+			wantTotal: 99700,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			toCharged := tc.toCharged
+			if toCharged.IsZero() {
+				toCharged = money.OneRate()
+			}
+
+			currency := tc.currency
+			if currency == "" {
+				currency = "USD"
+			}
+
+			breakdown, err := Apply(tc.coupons, tc.items, nil, toCharged, currency, now)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+
+			if len(breakdown.Discounts) != len(tc.want) {
+				t.Fatalf("Apply() discounts = %v, want %v", breakdown.Discounts, tc.want)
+			}
+
+			var discount money.Amount
+			for index, applied := range breakdown.Discounts {
+				if applied.Amount != tc.want[index] || breakdown.Redemptions[index].DiscountAmount != tc.want[index] {
+					t.Errorf("Apply() discount %d = %s, want %s", index, applied.Amount, tc.want[index])
+				}
+
+				discount += applied.Amount
+			}
+
+			if breakdown.DiscountAmount != discount || breakdown.Total != tc.wantTotal {
+				t.Errorf("Apply() discount = %s, total = %s, want %s, %s", breakdown.DiscountAmount, breakdown.Total, discount, tc.wantTotal)
+			}
+
+			if breakdown.Subtotal-breakdown.DiscountAmount != breakdown.Total {
+				t.Errorf("Apply() subtotal %s - discount %s != total %s", breakdown.Subtotal, breakdown.DiscountAmount, breakdown.Total)
+			}
+
+			unit, err := money.MinorUnit(currency)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := sum(Allocate(breakdown.DiscountAmount, tc.items, unit)); got != breakdown.DiscountAmount {
+				t.Errorf("Allocate() sums to %s, want %s", got, breakdown.DiscountAmount)
+			}
+		})
+	}
+}
+
+func TestApplyRejectsUnavailableCoupon(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	before, after := now.Add(-time.Hour), now.Add(time.Hour)
+	tenPercent := func(coupon models.Coupon) models.Coupon {
+		coupon.Type, coupon.DiscountValue = constant.CouponTypePercentage, 1000
+		return coupon
+	}
+
+	tests := []struct {
+		name   string
+		coupon models.Coupon
+		usage  int
+	}{
+		{name: "not active yet", coupon: tenPercent(models.Coupon{StartTime: &after})},
+		{name: "expired", coupon: tenPercent(models.Coupon{ExpireTime: &before})},
+		{name: "expires now", coupon: tenPercent(models.Coupon{ExpireTime: &now})},
+		{name: "fully redeemed", coupon: tenPercent(models.Coupon{MaxUses: 10, UsedCount: 10})},
+		{name: "used up by this user", coupon: tenPercent(models.Coupon{MaxUsesPerUser: 1}), usage: 1},
+		{name: "minimum spend", coupon: tenPercent(models.Coupon{MinSpend: 3001})},
+		{name: "zero percentage", coupon: models.Coupon{Type: constant.CouponTypePercentage}},
+		{name: "over a hundred percent", coupon: models.Coupon{Type: constant.CouponTypePercentage, DiscountValue: 10001}},
+		{name: "product not in cart", coupon: models.Coupon{Type: constant.CouponTypeBuyXGetY, BuyProductID: 99, BuyQty: 1, GetQty: 1}},
+		{name: "invalid buy quantity", coupon: models.Coupon{Type: constant.CouponTypeBuyXGetY, BuyProductID: 1, GetQty: 1}},
+		{name: "unknown type", coupon: models.Coupon{Type: "bogus", DiscountValue: 1000}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			coupon := tc.coupon
+			coupon.ID, coupon.Code = 1, "CODE"
+
+			_, err := Apply([]models.Coupon{coupon}, lines(1000, 2000), map[int64]int{1: tc.usage}, money.OneRate(), "USD", now)
+
+			var unavailable *constant.CouponUnavailableError
+			if !errors.As(err, &unavailable) || !errors.Is(err, constant.ErrInvalidCheckout) {
+				t.Fatalf("Apply() error = %v, want a CouponUnavailableError", err)
+			}
+		})
+	}
+}
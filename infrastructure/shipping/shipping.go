@@ -0,0 +1,218 @@
+package shipping
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"order/infrastructure/money"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	ProviderNone  = "none"
+	ProviderTable = "table"
+)
+
+const (
+	MethodStandard = "standard"
+	MethodExpress  = "express"
+	MethodSameDay  = "same_day"
+)
+
+var ErrUnsupportedDestination = errors.New("no shipping to destination")
+
+// Destination is where an order is shipped to. Region is optional, such as a state or province code.
+type Destination struct {
+	Country string
+	Region  string
+}
+
+// String formats the destination like ISO 3166-2, such as ID or ID-JK.
+func (d Destination) String() string {
+	if d.Region == "" {
+		return d.Country
+	}
+
+	return d.Country + "-" + d.Region
+}
+
+// Parcel is what is shipped: Weight is in grams and Quantity the number of units in it.
+type Parcel struct {
+	Weight   int64
+	Quantity int
+}
+
+// Option is one way to ship a parcel. Fee is in the base currency.
+type Option struct {
+	Method  string
+	Carrier string
+	Fee     money.Amount
+	MinDays int
+	MaxDays int
+}
+
+// Provider quotes the shipping options of a parcel, cheapest first.
+type Provider interface {
+	Quote(ctx context.Context, destination Destination, parcel Parcel) ([]Option, error)
+}
+
+// FreeShipping is the provider of a deployment that does not charge delivery, it offers one free
+// standard option everywhere.
+type FreeShipping struct{}
+
+func (FreeShipping) Quote(ctx context.Context, destination Destination, parcel Parcel) ([]Option, error) {
+	return []Option{{Method: MethodStandard}}, nil
+}
+
+// Rate prices one method of a zone: BaseFee, plus PerKgFee for every started kilogram, plus PerItemFee
+// for every unit. A rate with MaxWeight is not offered for heavier parcels.
+type Rate struct {
+	Method     string       `json:"method"`
+	Carrier    string       `json:"carrier"`
+	BaseFee    money.Amount `json:"base_fee"`
+	PerKgFee   money.Amount `json:"per_kg_fee"`
+	PerItemFee money.Amount `json:"per_item_fee"`
+	MaxWeight  int64        `json:"max_weight"`
+	MinDays    int          `json:"min_days"`
+	MaxDays    int          `json:"max_days"`
+}
+
+// Zone is the rates of one destination, a zone without a region covers the whole country.
+type Zone struct {
+	Country string `json:"country"`
+	Region  string `json:"region"`
+	Rates   []Rate `json:"rates"`
+}
+
+// TableProvider quotes from a fixed rate table. A region zone wins over the zone of its country.
+type TableProvider struct {
+	zones map[Destination]Zone
+}
+
+func NewTableProvider(zones []Zone) (*TableProvider, error) {
+	table := make(map[Destination]Zone, len(zones))
+	for _, zone := range zones {
+		zone.Country = strings.ToUpper(strings.TrimSpace(zone.Country))
+		zone.Region = strings.ToUpper(strings.TrimSpace(zone.Region))
+
+		if zone.Country == "" {
+			return nil, fmt.Errorf("shipping zone without a country")
+		}
+
+		methods := map[string]bool{}
+		for _, rate := range zone.Rates {
+			if rate.Method != MethodStandard && rate.Method != MethodExpress && rate.Method != MethodSameDay {
+				return nil, fmt.Errorf("shipping zone %s %s has an unknown method %q", zone.Country, zone.Region, rate.Method)
+			}
+
+			if methods[rate.Method] {
+				return nil, fmt.Errorf("shipping zone %s %s has a duplicate method %s", zone.Country, zone.Region, rate.Method)
+			}
+
+			if rate.BaseFee < 0 || rate.PerKgFee < 0 || rate.PerItemFee < 0 {
+				return nil, fmt.Errorf("shipping zone %s %s has a negative fee for %s", zone.Country, zone.Region, rate.Method)
+			}
+
+			methods[rate.Method] = true
+		}
+
+		key := Destination{Country: zone.Country, Region: zone.Region}
+		if _, isExist := table[key]; isExist {
+			return nil, fmt.Errorf("duplicate shipping zone %s %s", zone.Country, zone.Region)
+		}
+
+		table[key] = zone
+	}
+
+	return &TableProvider{zones: table}, nil
+}
+
+func (p *TableProvider) Quote(ctx context.Context, destination Destination, parcel Parcel) ([]Option, error) {
+	zone, isExist := p.zone(destination)
+	if !isExist {
+		return nil, fmt.Errorf("%w %s", ErrUnsupportedDestination, destination)
+	}
+
+	// every started kilogram is charged
+	kilograms := (parcel.Weight + 999) / 1000
+
+	options := make([]Option, 0, len(zone.Rates))
+	for _, rate := range zone.Rates {
+		if rate.MaxWeight > 0 && parcel.Weight > rate.MaxWeight {
+			continue
+		}
+
+		weightFee, err := rate.PerKgFee.Mul(kilograms)
+		if err != nil {
+			return nil, err
+		}
+
+		itemFee, err := rate.PerItemFee.Mul(int64(parcel.Quantity))
+		if err != nil {
+			return nil, err
+		}
+
+		fee, err := rate.BaseFee.Add(weightFee)
+		if err != nil {
+			return nil, err
+		}
+
+		fee, err = fee.Add(itemFee)
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(options, Option{
+			Method:  rate.Method,
+			Carrier: rate.Carrier,
+			Fee:     fee,
+			MinDays: rate.MinDays,
+			MaxDays: rate.MaxDays,
+		})
+	}
+
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].Fee < options[j].Fee
+	})
+
+	return options, nil
+}
+
+func (p *TableProvider) zone(destination Destination) (Zone, bool) {
+	country := strings.ToUpper(strings.TrimSpace(destination.Country))
+	region := strings.ToUpper(strings.TrimSpace(destination.Region))
+
+	if region != "" {
+		zone, isExist := p.zones[Destination{Country: country, Region: region}]
+		if isExist {
+			return zone, true
+		}
+	}
+
+	zone, isExist := p.zones[Destination{Country: country}]
+	return zone, isExist
+}
+
+type rateFile struct {
+	Zones []Zone `json:"zones"`
+}
+
+// NewFileProvider loads the rate table from a JSON file shaped like
+// {"zones": [{"country": "ID", "rates": [{"method": "standard", "carrier": "JNE", "base_fee": 9000, "per_kg_fee": 4000}]}]}.
+func NewFileProvider(path string) (*TableProvider, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file rateFile
+	err = json.Unmarshal(content, &file)
+	if err != nil {
+		return nil, fmt.Errorf("parse shipping rate file %s: %w", path, err)
+	}
+
+	return NewTableProvider(file.Zones)
+}
@@ -0,0 +1,241 @@
+package tax
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"order/infrastructure/money"
+	"os"
+	"strings"
+)
+
+const (
+	CalculatorNone  = "none"
+	CalculatorTable = "table"
+)
+
+const (
+	// ModeExclusive adds the tax on top of the price.
+	ModeExclusive = "exclusive"
+	// ModeInclusive treats the price as already containing the tax, the total does not change.
+	ModeInclusive = "inclusive"
+)
+
+// Jurisdiction is where an order is shipped to. Region is optional, such as a state or province code.
+type Jurisdiction struct {
+	Country string
+	Region  string
+}
+
+// Line is one order line to tax: Amount is what the customer pays for it after discounts.
+type Line struct {
+	ProductID  int64
+	CategoryID int
+	Amount     money.Amount
+}
+
+// Rate is a tax rate in basis points, 1100 is 11%. The tax table writes it as a percentage with up to
+// two decimals, such as 11 or 7.25, which is a whole number of basis points.
+type Rate int64
+
+func (r Rate) String() string {
+	return money.FromMinor(int64(r)).String() + "%"
+}
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	percentage, err := money.Parse(strings.Trim(string(data), `"`))
+	if err != nil {
+		return fmt.Errorf("invalid tax rate %s: %w", data, err)
+	}
+
+	*r = Rate(percentage.Minor())
+	return nil
+}
+
+// LineTax is the tax of the line at the same index.
+type LineTax struct {
+	ProductID int64
+	Rate      Rate
+	Amount    money.Amount
+}
+
+type Result struct {
+	Mode  string
+	Lines []LineTax
+	Total money.Amount
+}
+
+// Calculator taxes the lines of an order shipped to a jurisdiction, the lines are in currency.
+type Calculator interface {
+	Calculate(ctx context.Context, jurisdiction Jurisdiction, currency string, lines []Line) (Result, error)
+}
+
+// NoTax is the calculator of a deployment that does not charge tax, every line is taxed at zero.
+type NoTax struct{}
+
+func (NoTax) Calculate(ctx context.Context, jurisdiction Jurisdiction, currency string, lines []Line) (Result, error) {
+	result := Result{
+		Mode:  ModeExclusive,
+		Lines: make([]LineTax, len(lines)),
+	}
+
+	for index, line := range lines {
+		result.Lines[index] = LineTax{ProductID: line.ProductID}
+	}
+
+	return result, nil
+}
+
+// Rule is the tax of one jurisdiction. Categories overrides Rate for the product categories it lists,
+// a zero rate marks the category exempt.
+type Rule struct {
+	Country    string       `json:"country"`
+	Region     string       `json:"region"`
+	Mode       string       `json:"mode"`
+	Rate       Rate         `json:"rate"`
+	Categories map[int]Rate `json:"categories"`
+}
+
+// TableCalculator looks the rate up in a fixed table. A region rule wins over the rule of its country,
+// a region rule without a mode uses the mode of its country, and a destination with no rule is not taxed.
+type TableCalculator struct {
+	rules map[Jurisdiction]Rule
+}
+
+func NewTableCalculator(rules []Rule) (*TableCalculator, error) {
+	table := make(map[Jurisdiction]Rule, len(rules))
+	for _, rule := range rules {
+		rule.Country = strings.ToUpper(strings.TrimSpace(rule.Country))
+		rule.Region = strings.ToUpper(strings.TrimSpace(rule.Region))
+
+		if rule.Country == "" {
+			return nil, fmt.Errorf("tax rule without a country")
+		}
+
+		if rule.Mode != "" && rule.Mode != ModeExclusive && rule.Mode != ModeInclusive {
+			return nil, fmt.Errorf("tax rule %s %s has an unknown mode %q", rule.Country, rule.Region, rule.Mode)
+		}
+
+		if !validRate(rule.Rate) {
+			return nil, fmt.Errorf("tax rule %s %s has an invalid rate %s", rule.Country, rule.Region, rule.Rate)
+		}
+
+		for category, rate := range rule.Categories {
+			if !validRate(rate) {
+				return nil, fmt.Errorf("tax rule %s %s has an invalid rate %s for category %d", rule.Country, rule.Region, rate, category)
+			}
+		}
+
+		key := Jurisdiction{Country: rule.Country, Region: rule.Region}
+		if _, isExist := table[key]; isExist {
+			return nil, fmt.Errorf("duplicate tax rule %s %s", rule.Country, rule.Region)
+		}
+
+		table[key] = rule
+	}
+
+	for key, rule := range table {
+		if rule.Mode != "" {
+			continue
+		}
+
+		rule.Mode = table[Jurisdiction{Country: key.Country}].Mode
+		if rule.Mode == "" {
+			rule.Mode = ModeExclusive
+		}
+
+		table[key] = rule
+	}
+
+	return &TableCalculator{rules: table}, nil
+}
+
+func validRate(rate Rate) bool {
+	return rate >= 0 && rate <= 100*100
+}
+
+// Calculate rounds the tax of every line half away from zero to the minor unit of currency, the order
+// tax is the sum of its lines.
+func (c *TableCalculator) Calculate(ctx context.Context, jurisdiction Jurisdiction, currency string, lines []Line) (Result, error) {
+	rule, isExist := c.rule(jurisdiction)
+	if !isExist {
+		return NoTax{}.Calculate(ctx, jurisdiction, currency, lines)
+	}
+
+	result := Result{
+		Mode:  rule.Mode,
+		Lines: make([]LineTax, len(lines)),
+	}
+
+	for index, line := range lines {
+		rate, isExist := rule.Categories[line.CategoryID]
+		if !isExist {
+			rate = rule.Rate
+		}
+
+		// an inclusive price is 100+rate percent of its net price
+		denominator := int64(100 * 100)
+		if rule.Mode == ModeInclusive {
+			denominator += int64(rate)
+		}
+
+		share, err := money.RateFromFraction(int64(rate), denominator)
+		if err != nil {
+			return Result{}, err
+		}
+
+		amount, err := line.Amount.ConvertTo(share, currency)
+		if err != nil {
+			return Result{}, err
+		}
+
+		result.Lines[index] = LineTax{
+			ProductID: line.ProductID,
+			Rate:      rate,
+			Amount:    amount,
+		}
+
+		result.Total, err = result.Total.Add(amount)
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	return result, nil
+}
+
+func (c *TableCalculator) rule(jurisdiction Jurisdiction) (Rule, bool) {
+	country := strings.ToUpper(strings.TrimSpace(jurisdiction.Country))
+	region := strings.ToUpper(strings.TrimSpace(jurisdiction.Region))
+
+	if region != "" {
+		rule, isExist := c.rules[Jurisdiction{Country: country, Region: region}]
+		if isExist {
+			return rule, true
+		}
+	}
+
+	rule, isExist := c.rules[Jurisdiction{Country: country}]
+	return rule, isExist
+}
+
+type tableFile struct {
+	Jurisdictions []Rule `json:"jurisdictions"`
+}
+
+// NewFileCalculator loads the tax table from a JSON file shaped like
+// {"jurisdictions": [{"country": "ID", "mode": "inclusive", "rate": 11, "categories": {"3": 0}}]}.
+func NewFileCalculator(path string) (*TableCalculator, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file tableFile
+	err = json.Unmarshal(content, &file)
+	if err != nil {
+		return nil, fmt.Errorf("parse tax table file %s: %w", path, err)
+	}
+
+	return NewTableCalculator(file.Jurisdictions)
+}
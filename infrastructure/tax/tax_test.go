@@ -0,0 +1,285 @@
+package tax
+
+import (
+	"context"
+	"encoding/json"
+	"order/infrastructure/money"
+	"testing"
+)
+
+func testCalculator(t *testing.T) *TableCalculator {
+	t.Helper()
+
+	var file tableFile
+	err := json.Unmarshal([]byte(`{"jurisdictions": [
+		{"country": "ID", "mode": "inclusive", "rate": 11, "categories": {"3": 0}},
+		{"country": "MY", "mode": "exclusive", "rate": 10, "categories": {"3": 0, "4": "6.5"}},
+		{"country": "US", "mode": "exclusive", "rate": 0},
+		{"country": "US", "region": "CA", "rate": 7.25},
+		{"country": "US", "region": "NY", "rate": 4, "categories": {"3": 0}},
+		{"country": "JP", "mode": "inclusive", "rate": 10},
+		{"country": "JP", "region": "13", "rate": 8},
+		{"country": "SG", "region": "01", "rate": 9}
+	]}`), &file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calculator, err := NewTableCalculator(file.Jurisdictions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return calculator
+}
+
+func TestTableCalculatorCalculate(t *testing.T) {
+	calculator := testCalculator(t)
+
+	tests := []struct {
+		name         string
+		jurisdiction Jurisdiction
+		currency     string
+		lines        []Line
+		wantMode     string
+		wantRates    []Rate
+		wantTaxes    []money.Amount
+		wantTotal    money.Amount
+	}{
+		{
+			name:         "inclusive takes the tax out of the price",
+			jurisdiction: Jurisdiction{Country: "ID"},
+			lines:        []Line{{Amount: 11100}, {Amount: 10000}},
+			wantMode:     ModeInclusive,
+			wantRates:    []Rate{1100, 1100},
+			wantTaxes:    []money.Amount{1100, 991},
+			wantTotal:    2091,
+		},
+		{
+			name:         "exclusive adds the tax on top",
+			jurisdiction: Jurisdiction{Country: "MY"},
+			lines:        []Line{{Amount: 10000}, {Amount: 12345}},
+			wantMode:     ModeExclusive,
+			wantRates:    []Rate{1000, 1000},
+			wantTaxes:    []money.Amount{1000, 1235},
+			wantTotal:    2235,
+		},
+		{
+			name:         "every line is rounded on its own and the total is their sum",
+			jurisdiction: Jurisdiction{Country: "US", Region: "CA"},
+			lines:        []Line{{Amount: 1000}, {Amount: 1000}, {Amount: 1000}},
+			wantMode:     ModeExclusive,
+			wantRates:    []Rate{725, 725, 725},
+			wantTaxes:    []money.Amount{73, 73, 73},
+			wantTotal:    219,
+		},
+		{
+			name:         "jurisdiction is matched case and space insensitively",
+			jurisdiction: Jurisdiction{Country: " us ", Region: "ca"},
+			lines:        []Line{{Amount: 10000}},
+			wantMode:     ModeExclusive,
+			wantRates:    []Rate{725},
+			wantTaxes:    []money.Amount{725},
+			wantTotal:    725,
+		},
+		{
+			name:         "region without a rule falls back to its country",
+			jurisdiction: Jurisdiction{Country: "US", Region: "TX"},
+			lines:        []Line{{Amount: 10000}},
+			wantMode:     ModeExclusive,
+			wantRates:    []Rate{0},
+			wantTaxes:    []money.Amount{0},
+			wantTotal:    0,
+		},
+		{
+			name:         "region rule inherits the mode of its country",
+			jurisdiction: Jurisdiction{Country: "JP", Region: "13"},
+			lines:        []Line{{Amount: 10800}},
+			wantMode:     ModeInclusive,
+			wantRates:    []Rate{800},
+			wantTaxes:    []money.Amount{800},
+			wantTotal:    800,
+		},
+		{
+			name:         "region rule of a country without a rule is exclusive",
+			jurisdiction: Jurisdiction{Country: "SG", Region: "01"},
+			lines:        []Line{{Amount: 10000}},
+			wantMode:     ModeExclusive,
+			wantRates:    []Rate{900},
+			wantTaxes:    []money.Amount{900},
+			wantTotal:    900,
+		},
+		{
+			name:         "category overrides the rate",
+			jurisdiction: Jurisdiction{Country: "MY"},
+			lines:        []Line{{CategoryID: 3, Amount: 10000}, {CategoryID: 4, Amount: 10000}, {CategoryID: 5, Amount: 10000}},
+			wantMode:     ModeExclusive,
+			wantRates:    []Rate{0, 650, 1000},
+			wantTaxes:    []money.Amount{0, 650, 1000},
+			wantTotal:    1650,
+		},
+		{
+			name:         "region rule has its own categories",
+			jurisdiction: Jurisdiction{Country: "US", Region: "NY"},
+			lines:        []Line{{CategoryID: 3, Amount: 10000}, {CategoryID: 1, Amount: 10000}},
+			wantMode:     ModeExclusive,
+			wantRates:    []Rate{0, 400},
+			wantTaxes:    []money.Amount{0, 400},
+			wantTotal:    400,
+		},
+		{
+			name:         "exempt inclusive category",
+			jurisdiction: Jurisdiction{Country: "ID"},
+			lines:        []Line{{CategoryID: 3, Amount: 11100}},
+			wantMode:     ModeInclusive,
+			wantRates:    []Rate{0},
+			wantTaxes:    []money.Amount{0},
+			wantTotal:    0,
+		},
+		{
+			name:         "rounded to whole yen",
+			jurisdiction: Jurisdiction{Country: "US", Region: "CA"},
+			currency:     "JPY",
+			lines:        []Line{{Amount: 100000}, {Amount: 100000}},
+			wantMode:     ModeExclusive,
+			wantRates:    []Rate{725, 725},
+			wantTaxes:    []money.Amount{7300, 7300},
+			wantTotal:    14600,
+		},
+		{
+			name:         "inclusive rounded to whole yen",
+			jurisdiction: Jurisdiction{Country: "JP"},
+			currency:     "JPY",
+			lines:        []Line{{Amount: 99900}},
+			wantMode:     ModeInclusive,
+			wantRates:    []Rate{1000},
+			wantTaxes:    []money.Amount{9100},
+			wantTotal:    9100,
+		},
+		{
+			name:         "destination without a rule is not taxed",
+			jurisdiction: Jurisdiction{Country: "FR"},
+			lines:        []Line{{Amount: 10000}},
+			wantMode:     ModeExclusive,
+			wantRates:    []Rate{0},
+			wantTaxes:    []money.Amount{0},
+			wantTotal:    0,
+		},
+		{
+			name:         "no lines",
+			jurisdiction: Jurisdiction{Country: "ID"},
+			wantMode:     ModeInclusive,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			currency := tc.currency
+			if currency == "" {
+				currency = "IDR"
+			}
+
+			for index := range tc.lines {
+				tc.lines[index].ProductID = int64(index + 1)
+			}
+
+			result, err := calculator.Calculate(context.Background(), tc.jurisdiction, currency, tc.lines)
+			if err != nil {
+				t.Fatalf("Calculate() error = %v", err)
+			}
+
+			if result.Mode != tc.wantMode || result.Total != tc.wantTotal || len(result.Lines) != len(tc.lines) {
+				t.Fatalf("Calculate() = %s %s over %d lines, want %s %s over %d", result.Mode, result.Total, len(result.Lines), tc.wantMode, tc.wantTotal, len(tc.lines))
+			}
+
+			var total money.Amount
+			for index, line := range result.Lines {
+				if line.ProductID != tc.lines[index].ProductID || line.Rate != tc.wantRates[index] || line.Amount != tc.wantTaxes[index] {
+					t.Errorf("Calculate() line %d = %+v, want rate %s tax %s", index, line, tc.wantRates[index], tc.wantTaxes[index])
+				}
+
+				total += line.Amount
+			}
+
+			if total != result.Total {
+				t.Errorf("Calculate() lines sum to %s, total is %s", total, result.Total)
+			}
+		})
+	}
+}
+
+func TestNewTableCalculatorRejectsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []Rule
+	}{
+		{name: "no country", rules: []Rule{{Rate: 1000}}},
+		{name: "unknown mode", rules: []Rule{{Country: "ID", Mode: "gross", Rate: 1000}}},
+		{name: "negative rate", rules: []Rule{{Country: "ID", Rate: -1}}},
+		{name: "rate over a hundred percent", rules: []Rule{{Country: "ID", Rate: 10001}}},
+		{name: "invalid category rate", rules: []Rule{{Country: "ID", Rate: 1000, Categories: map[int]Rate{3: 10001}}}},
+		{name: "duplicate rule", rules: []Rule{{Country: "US", Region: "CA"}, {Country: "us", Region: " ca "}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewTableCalculator(tc.rules)
+			if err == nil {
+				t.Errorf("NewTableCalculator() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestRateUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Rate
+		wantErr bool
+	}{
+		{input: `11`, want: 1100},
+		{input: `7.25`, want: 725},
+		{input: `"6.5"`, want: 650},
+		{input: `0`, want: 0},
+		{input: `7.255`, wantErr: true},
+		{input: `"abc"`, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			var got Rate
+			err := json.Unmarshal([]byte(tc.input), &got)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("UnmarshalJSON(%s) error = %v, want error %v", tc.input, err, tc.wantErr)
+			}
+
+			if got != tc.want {
+				t.Errorf("UnmarshalJSON(%s) = %d, want %d", tc.input, got, tc.want)
+			}
+		})
+	}
+
+	if got := Rate(725).String(); got != "7.25%" {
+		t.Errorf("String() = %s, want 7.25%%", got)
+	}
+}
+
+func TestNoTax(t *testing.T) {
+	result, err := NoTax{}.Calculate(context.Background(), Jurisdiction{Country: "ID"}, "IDR", []Line{{ProductID: 7, Amount: 10000}})
+	if err != nil || result.Mode != ModeExclusive || result.Total != 0 || len(result.Lines) != 1 || result.Lines[0] != (LineTax{ProductID: 7}) {
+		t.Errorf("Calculate() = %+v, %v", result, err)
+	}
+}
+
+// The table shipped with the service must keep loading.
+func TestNewFileCalculator(t *testing.T) {
+	calculator, err := NewFileCalculator("../../files/tax_rates.json")
+	if err != nil {
+		t.Fatalf("NewFileCalculator() error = %v", err)
+	}
+
+	result, err := calculator.Calculate(context.Background(), Jurisdiction{Country: "US", Region: "CA"}, "USD", []Line{{Amount: 10000}})
+	if err != nil || result.Total != 725 {
+		t.Errorf("Calculate(US-CA) = %+v, %v, want a tax of 7.25", result, err)
+	}
+}
@@ -0,0 +1,66 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"order/infrastructure/log"
+	kafkaOrder "order/kafka"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const dlqReplayGroupID = "order-dlq-replay"
+
+// ReplayDLQ moves up to limit messages from a dead-letter topic back onto the topic they failed on,
+// stopping once the topic stays idle for idleTimeout. A limit of 0 replays everything.
+func ReplayDLQ(ctx context.Context, brokers []string, dlqTopic string, producer *kafkaOrder.KafkaProducer, limit int, idleTimeout time.Duration) (int, error) {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: brokers,
+		Topic:   dlqTopic,
+		GroupID: dlqReplayGroupID,
+	})
+	defer reader.Close()
+
+	replayed := 0
+	for limit == 0 || replayed < limit {
+		fetchCtx, cancel := context.WithTimeout(ctx, idleTimeout)
+		message, err := reader.FetchMessage(fetchCtx)
+		cancel()
+
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				return replayed, nil
+			}
+
+			return replayed, err
+		}
+
+		topic := headerValue(message, HeaderOriginalTopic)
+		if topic == "" {
+			topic = strings.TrimSuffix(message.Topic, DLQSuffix)
+		}
+
+		// the replayed message starts with a fresh retry budget
+		err = producer.PublishMessage(ctx, kafka.Message{
+			Topic:   topic,
+			Key:     message.Key,
+			Value:   message.Value,
+			Headers: withoutDeadLetterHeaders(message.Headers),
+		})
+		if err != nil {
+			return replayed, err
+		}
+
+		err = reader.CommitMessages(ctx, message)
+		if err != nil {
+			return replayed, err
+		}
+
+		replayed++
+		log.Logger.Printf("[KAFKA] Replayed %s offset %d to %s", message.Topic, message.Offset, topic)
+	}
+
+	return replayed, nil
+}
@@ -0,0 +1,66 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"order/infrastructure/log"
+	kafkaOrder "order/kafka"
+	"order/models"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"gorm.io/gorm"
+)
+
+// Ledger records processed events in the same transaction as their side effects.
+type Ledger interface {
+	ProcessEventOnce(ctx context.Context, event *models.ProcessedEvent, fn func(tx *gorm.DB) error) (bool, error)
+}
+
+// TxHandler is a Handler whose writes go through the ledger transaction.
+type TxHandler[T any] func(ctx context.Context, tx *gorm.DB, event T, message kafka.Message) error
+
+// Once turns handler into a Handler that runs at most once per message for the named consumer. The
+// ledger entry and the handler writes commit together, so a redelivered message is skipped while a
+// failed one is retried from scratch.
+func Once[T any](ledger Ledger, consumer string, handler TxHandler[T]) Handler[T] {
+	return func(ctx context.Context, event T, message kafka.Message) error {
+		processedEvent := models.ProcessedEvent{
+			Consumer:      consumer,
+			EventKey:      EventKey(ctx, message),
+			ProcessedTime: time.Now(),
+		}
+
+		processed, err := ledger.ProcessEventOnce(ctx, &processedEvent, func(tx *gorm.DB) error {
+			return handler(ctx, tx, event, message)
+		})
+		if err != nil {
+			return err
+		}
+
+		if !processed {
+			log.Logger.Printf("[KAFKA] Skip already processed event %s for %s", processedEvent.EventKey, consumer)
+		}
+
+		return nil
+	}
+}
+
+// EventKey identifies a message by its envelope id, falling back to its position in the topic for
+// producers that do not send envelopes.
+func EventKey(ctx context.Context, message kafka.Message) string {
+	if envelope, ok := kafkaOrder.EnvelopeFromContext(ctx); ok && envelope.ID != "" {
+		return envelope.ID
+	}
+
+	return fmt.Sprintf("%s/%d/%d", message.Topic, message.Partition, message.Offset)
+}
+
+// eventProducer names the service that published the event being handled.
+func eventProducer(ctx context.Context) string {
+	if envelope, ok := kafkaOrder.EnvelopeFromContext(ctx); ok {
+		return envelope.Producer
+	}
+
+	return ""
+}
@@ -2,94 +2,66 @@ package consumer
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"order/cmd/order/service"
 	"order/infrastructure/constant"
 	"order/infrastructure/log"
 	kafkaOrder "order/kafka"
 	"order/models"
-	"time"
 
 	"github.com/segmentio/kafka-go"
+	"gorm.io/gorm"
 )
 
 type PaymentFailedEvent struct {
-	Reader       *kafka.Reader
-	Producer     kafkaOrder.KafkaProducer
-	OrderService service.OrderService
+	Producer     *kafkaOrder.KafkaProducer
+	OrderService *service.OrderService
 }
 
-func NewPaymentFailedConsumer(brokers []string, topic string, orderService service.OrderService, kafkaProducer kafkaOrder.KafkaProducer) *PaymentFailedEvent {
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers: brokers,
-		Topic:   topic,
-		GroupID: "order",
-	})
-
+func NewPaymentFailedConsumer(orderService *service.OrderService, kafkaProducer *kafkaOrder.KafkaProducer) *PaymentFailedEvent {
 	return &PaymentFailedEvent{
-		Reader:       reader,
 		OrderService: orderService,
 		Producer:     kafkaProducer,
 	}
 }
 
-func (c *PaymentFailedEvent) Start(ctx context.Context) {
-	log.Logger.Println("[KAFKA] Listening to topic: payment.failed")
-
-	for {
-		message, err := c.Reader.ReadMessage(ctx)
-		if err != nil {
-			log.Logger.Println("[KAFKA] Error Read Message: ", err)
-			continue
-		}
-
-		var event models.PaymentUpdateStatusEvent
-		err = json.Unmarshal(message.Value, &event)
-		if err != nil {
-			log.Logger.Println("[KAFKA] EArror Unmarshal event message value: ", err)
-			continue
-		}
-
-		// update DB status order
-		err = c.OrderService.UpdateOrderStatus(ctx, event.OrderID, constant.OrderStatusCancelled)
-		if err != nil {
-			log.Logger.Println("[KAFKA] Error update order status")
-			continue
-		}
-
-		// order info
-		orderInfo, err := c.OrderService.GetOrderInfoByOrderID(ctx, event.OrderID)
-		if err != nil {
-			log.Logger.Println("[KAFKA] Error get order info by order id")
-			continue
-		}
+// Handle is the payment.failed handler run by the consumer runtime, at most once per message.
+func (c *PaymentFailedEvent) Handle() Handler[models.PaymentUpdateStatusEvent] {
+	return Once(c.OrderService, constant.TopicPaymentFailed, c.handleTx)
+}
 
-		// order detail info
-		orderDetailInfo, err := c.OrderService.GetOrderDetailByID(ctx, orderInfo.OrderDetailID)
+// handleTx cancels the order and queues the release of its stock in the ledger transaction, so a
+// redelivered payment.failed can never restock the products twice.
+func (c *PaymentFailedEvent) handleTx(ctx context.Context, tx *gorm.DB, event models.PaymentUpdateStatusEvent, message kafka.Message) error {
+	// update DB status order and publish the event returning the stock
+	err := c.OrderService.TransitionOrderTx(ctx, tx, &models.UpdateOrderStatusParam{
+		OrderID:       event.OrderID,
+		Status:        constant.OrderStatusCancelled,
+		Reason:        "payment failed",
+		ActorType:     constant.ActorTypePayment,
+		ActorID:       eventProducer(ctx),
+		SourceEventID: EventKey(ctx, message),
+	}, func(order models.Order, orderDetail models.OrderDetail) ([]models.OutboxMessage, error) {
+		stockReturn, err := kafkaOrder.NewStockReturnMessage(ctx, order, orderDetail, "payment failed")
 		if err != nil {
-			log.Logger.Println("[KAFKA] Error get order detail by order detail id")
-			continue
+			return nil, Permanent(fmt.Errorf("unmarshal product from order detail: %w", err))
 		}
 
-		// construct product
-		productStockUpdate := make([]models.ProductItem, 0)
-		err = json.Unmarshal([]byte(orderDetailInfo.Products), &productStockUpdate)
-		if err != nil {
-			log.Logger.Println("[KAFKA] Error unmarshal product from order detail")
-			continue
+		return []models.OutboxMessage{stockReturn}, nil
+	})
+	if err != nil {
+		if errors.Is(err, constant.ErrInvalidStatusTransition) {
+			log.Logger.Println("[KAFKA] Skip payment.failed event: ", err)
+			return nil
 		}
 
-		// publish event product stock.rollback
-		updateStockEvent := models.ProductStockUpdateEvent{
-			OrderID:   event.OrderID,
-			Products:  productStockUpdate,
-			EventTime: time.Now(),
+		if errors.Is(err, constant.ErrOrderNotFound) {
+			return Permanent(err)
 		}
 
-		err = c.Producer.PublishProductStockRollback(ctx, updateStockEvent)
-		if err != nil {
-			log.Logger.Println("[KAFKA] Error publish product stock rollback event")
-			continue
-		}
+		return fmt.Errorf("update order status: %w", err)
 	}
+
+	return nil
 }
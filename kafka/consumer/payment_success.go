@@ -2,7 +2,8 @@ package consumer
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"order/cmd/order/service"
 	"order/infrastructure/constant"
 	"order/infrastructure/log"
@@ -10,94 +11,63 @@ import (
 	"order/models"
 
 	"github.com/segmentio/kafka-go"
+	"gorm.io/gorm"
 )
 
 type PaymentSuccessConsumer struct {
-	Reader       *kafka.Reader
-	Producer     kafkaOrder.KafkaProducer
-	OrderService service.OrderService
+	Producer     *kafkaOrder.KafkaProducer
+	OrderService *service.OrderService
 }
 
-func NewPaymentSuccessConsumer(brokers []string, topic string, orderService service.OrderService, kafkaProducer kafkaOrder.KafkaProducer) *PaymentSuccessConsumer {
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers: brokers,
-		Topic:   topic,
-		GroupID: "order",
-	})
-
+func NewPaymentSuccessConsumer(orderService *service.OrderService, kafkaProducer *kafkaOrder.KafkaProducer) *PaymentSuccessConsumer {
 	return &PaymentSuccessConsumer{
-		Reader:       reader,
 		OrderService: orderService,
 		Producer:     kafkaProducer,
 	}
 }
 
-func (c *PaymentSuccessConsumer) Start(ctx context.Context) {
-	log.Logger.Println("[KAFKA] Listening to topic: payment.success")
+// Handle is the payment.success handler run by the consumer runtime, at most once per message.
+func (c *PaymentSuccessConsumer) Handle() Handler[models.PaymentUpdateStatusEvent] {
+	return Once(c.OrderService, constant.TopicPaymentSuccess, c.handleTx)
+}
 
-	for {
-		message, err := c.Reader.ReadMessage(ctx)
-		if err != nil {
-			log.Logger.Println("[KAFKA] Error Read Message: ", err)
-			continue
+// handleTx completes the order and, for an order holding a stock reservation, queues its confirmation
+// in the ledger transaction.
+func (c *PaymentSuccessConsumer) handleTx(ctx context.Context, tx *gorm.DB, event models.PaymentUpdateStatusEvent, message kafka.Message) error {
+	log.Logger.Printf("[KAFKA] Received payment.success event for Order ID %d\n", event.OrderID)
+
+	// update DB
+	err := c.OrderService.TransitionOrderTx(ctx, tx, &models.UpdateOrderStatusParam{
+		OrderID:       event.OrderID,
+		Status:        constant.OrderStatusCompleted,
+		Reason:        "payment success",
+		ActorType:     constant.ActorTypePayment,
+		ActorID:       eventProducer(ctx),
+		SourceEventID: EventKey(ctx, message),
+	}, func(order models.Order, orderDetail models.OrderDetail) ([]models.OutboxMessage, error) {
+		if order.StockReservationID == "" {
+			return nil, nil
 		}
 
-		var event models.PaymentUpdateStatusEvent
-		err = json.Unmarshal(message.Value, &event)
+		stockConfirm, err := kafkaOrder.NewStockConfirmMessage(ctx, order)
 		if err != nil {
-			log.Logger.Println("[KAFKA] EArror Unmarshal event message value: ", err)
-			continue
+			return nil, err
 		}
 
-		log.Logger.Printf("[KAFKA] Received payment.success event for Order ID %d\n", event.OrderID)
-
-		// update DB
-		err = c.OrderService.UpdateOrderStatus(ctx, event.OrderID, constant.OrderStatusCompleted)
-		if err != nil {
-			log.Logger.Println("[KAFKA] Error update order status")
-			continue
+		return []models.OutboxMessage{stockConfirm}, nil
+	})
+	if err != nil {
+		if errors.Is(err, constant.ErrInvalidStatusTransition) {
+			log.Logger.Println("[KAFKA] Skip payment.success event: ", err)
+			return nil
 		}
 
-		// // get order info from db
-		// order, err := c.OrderService.GetOrderInfoByOrderID(ctx, event.OrderID)
-		// if err != nil {
-		// 	log.Logger.Println("[KAFKA] Error get order info by order id")
-		// 	continue
-		// }
-
-		// // get order detail from db
-		// orderDetail, err := c.OrderService.GetOrderDetailByID(ctx, order.OrderDetailID)
-		// if err != nil {
-		// 	log.Logger.Println("[KAFKA] Error get order detail by id")
-		// 	continue
-		// }
-
-		// // get product list from order detail
-		// var products []models.CheckoutItem
-		// err = json.Unmarshal([]byte(orderDetail.Products), &products)
-		// if err != nil {
-		// 	log.Logger.Println("[KAFKA] Error unmarshal product list from order detail")
-		// 	continue
-		// }
-
-		// // public event product service
-		// err = c.Producer.PublishProductStockUpdate(ctx, models.ProductStockUpdateEvent{
-		// 	OrderID:   event.OrderID,
-		// 	Products:  convertCheckoutItemsToProductItems(products),
-		// 	EventTime: time.Now(),
-		// })
-	}
-}
-
-func convertCheckoutItemsToProductItems(items []models.CheckoutItem) []models.ProductItem {
-	result := make([]models.ProductItem, len(items))
-
-	for index, item := range items {
-		result[index] = models.ProductItem{
-			ProductID: item.ProductID,
-			Qty:       item.Quantity,
+		if errors.Is(err, constant.ErrOrderNotFound) {
+			return Permanent(err)
 		}
+
+		return fmt.Errorf("update order status: %w", err)
 	}
 
-	return result
+	return nil
 }
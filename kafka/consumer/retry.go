@@ -0,0 +1,200 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"order/infrastructure/log"
+	kafkaOrder "order/kafka"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DLQSuffix = ".dlq"
+
+	HeaderError             = "x-error"
+	HeaderAttempts          = "x-attempts"
+	HeaderOriginalTopic     = "x-original-topic"
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderFailedAt          = "x-failed-at"
+)
+
+const (
+	defaultMaxAttempts     = 5
+	defaultRetryBackoff    = 200 * time.Millisecond
+	defaultMaxRetryBackoff = 10 * time.Second
+)
+
+// RetryPolicy is the budget a message gets before it is parked on the dead-letter topic.
+type RetryPolicy struct {
+	MaxAttempts     int
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+}
+
+func NewRetryPolicy(maxAttempts int, retryBackoff, maxRetryBackoff time.Duration) RetryPolicy {
+	policy := RetryPolicy{
+		MaxAttempts:     maxAttempts,
+		RetryBackoff:    retryBackoff,
+		MaxRetryBackoff: maxRetryBackoff,
+	}
+
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = defaultMaxAttempts
+	}
+
+	if policy.RetryBackoff <= 0 {
+		policy.RetryBackoff = defaultRetryBackoff
+	}
+
+	if policy.MaxRetryBackoff <= 0 {
+		policy.MaxRetryBackoff = defaultMaxRetryBackoff
+	}
+
+	return policy
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.RetryBackoff << (attempt - 1)
+	if backoff <= 0 || backoff > p.MaxRetryBackoff {
+		backoff = p.MaxRetryBackoff
+	}
+
+	return backoff
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks an error that retrying cannot fix, such as a malformed payload. The message goes
+// to the dead-letter topic right away.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+func DLQTopic(topic string) string {
+	return topic + DLQSuffix
+}
+
+// handleWithRetry runs handle until it succeeds, fails permanently or the retry budget is spent, in
+// which case the message is published to the dead-letter topic. An error is returned only when ctx is
+// done before the message was handled or dead-lettered, so its offset must not be committed.
+func handleWithRetry(ctx context.Context, producer kafkaOrder.KafkaProducer, policy RetryPolicy, message kafka.Message, handle func(ctx context.Context, message kafka.Message) error) error {
+	var err error
+	attempt := 1
+
+	// an attempt that already started is allowed to finish on shutdown, only further retries stop
+	handleCtx := context.WithoutCancel(ctx)
+
+	for ; attempt <= policy.MaxAttempts; attempt++ {
+		err = handle(handleCtx, message)
+		if err == nil {
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempt == policy.MaxAttempts {
+			break
+		}
+
+		log.Logger.WithFields(logrus.Fields{
+			"topic":   message.Topic,
+			"offset":  message.Offset,
+			"attempt": attempt,
+			"err":     err.Error(),
+		}).Warn("[KAFKA] Handle message failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(policy.backoff(attempt)):
+		}
+	}
+
+	var permanent *permanentError
+	if ctx.Err() != nil && !errors.As(err, &permanent) {
+		return ctx.Err()
+	}
+
+	log.Logger.WithFields(logrus.Fields{
+		"topic":    message.Topic,
+		"offset":   message.Offset,
+		"attempts": attempt,
+		"err":      err.Error(),
+	}).Error("[KAFKA] Handle message failed, sending to dead-letter topic")
+
+	deadLetter := deadLetterMessage(message, err, attempt)
+	for {
+		err = producer.PublishMessage(handleCtx, deadLetter)
+		if err == nil {
+			return nil
+		}
+
+		log.Logger.WithFields(logrus.Fields{
+			"topic": deadLetter.Topic,
+			"err":   err.Error(),
+		}).Error("[KAFKA] Publish to dead-letter topic failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(policy.MaxRetryBackoff):
+		}
+	}
+}
+
+func deadLetterMessage(message kafka.Message, err error, attempts int) kafka.Message {
+	headers := append(withoutDeadLetterHeaders(message.Headers),
+		kafka.Header{Key: HeaderError, Value: []byte(err.Error())},
+		kafka.Header{Key: HeaderAttempts, Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: HeaderOriginalTopic, Value: []byte(message.Topic)},
+		kafka.Header{Key: HeaderOriginalPartition, Value: []byte(strconv.Itoa(message.Partition))},
+		kafka.Header{Key: HeaderOriginalOffset, Value: []byte(strconv.FormatInt(message.Offset, 10))},
+		kafka.Header{Key: HeaderFailedAt, Value: []byte(time.Now().Format(time.RFC3339Nano))},
+	)
+
+	return kafka.Message{
+		Topic:   DLQTopic(message.Topic),
+		Key:     message.Key,
+		Value:   message.Value,
+		Headers: headers,
+	}
+}
+
+// withoutDeadLetterHeaders drops the headers describing a previous dead-lettering.
+func withoutDeadLetterHeaders(headers []kafka.Header) []kafka.Header {
+	result := make([]kafka.Header, 0, len(headers))
+	for _, header := range headers {
+		switch header.Key {
+		case HeaderError, HeaderAttempts, HeaderOriginalTopic, HeaderOriginalPartition, HeaderOriginalOffset, HeaderFailedAt:
+			continue
+		}
+
+		result = append(result, header)
+	}
+
+	return result
+}
+
+func headerValue(message kafka.Message, key string) string {
+	for _, header := range message.Headers {
+		if header.Key == key {
+			return string(header.Value)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,182 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"order/infrastructure/log"
+	kafkaOrder "order/kafka"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const defaultGroupID = "order"
+
+// Handler processes one decoded event. The raw message is passed along for its key, headers and offset.
+type Handler[T any] func(ctx context.Context, event T, message kafka.Message) error
+
+// Runner is anything the Runtime can run until its context is done.
+type Runner interface {
+	Name() string
+	Run(ctx context.Context) error
+	Close() error
+}
+
+type Config struct {
+	Brokers     []string
+	Topic       string
+	GroupID     string
+	Concurrency int
+	RetryPolicy RetryPolicy
+}
+
+// Consumer reads a topic with Concurrency readers of the same group, validates and decodes every
+// message envelope into T and passes it to the handler. Both JSON and protobuf messages are
+// accepted, whatever the topic is currently published as. Offsets are committed only after the
+// handler succeeded or the message was parked on the dead-letter topic.
+type Consumer[T any] struct {
+	Config   Config
+	Readers  []*kafka.Reader
+	Producer *kafkaOrder.KafkaProducer
+	Handler  Handler[T]
+}
+
+func New[T any](cfg Config, producer *kafkaOrder.KafkaProducer, handler Handler[T]) *Consumer[T] {
+	if cfg.GroupID == "" {
+		cfg.GroupID = defaultGroupID
+	}
+
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+
+	cfg.RetryPolicy = NewRetryPolicy(cfg.RetryPolicy.MaxAttempts, cfg.RetryPolicy.RetryBackoff, cfg.RetryPolicy.MaxRetryBackoff)
+
+	readers := make([]*kafka.Reader, cfg.Concurrency)
+	for index := range readers {
+		readers[index] = kafka.NewReader(kafka.ReaderConfig{
+			Brokers: cfg.Brokers,
+			Topic:   cfg.Topic,
+			GroupID: cfg.GroupID,
+		})
+	}
+
+	return &Consumer[T]{
+		Config:   cfg,
+		Readers:  readers,
+		Producer: producer,
+		Handler:  handler,
+	}
+}
+
+func (c *Consumer[T]) Name() string {
+	return c.Config.Topic
+}
+
+// Run consumes until ctx is done. The message being handled when ctx is cancelled is finished first.
+func (c *Consumer[T]) Run(ctx context.Context) error {
+	log.Logger.Printf("[KAFKA] Listening to topic: %s with %d readers", c.Config.Topic, len(c.Readers))
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(c.Readers))
+
+	for index, reader := range c.Readers {
+		wg.Add(1)
+
+		go func(index int, reader *kafka.Reader) {
+			defer wg.Done()
+			errs[index] = c.consume(ctx, reader)
+		}(index, reader)
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (c *Consumer[T]) consume(ctx context.Context, reader *kafka.Reader) error {
+	for {
+		message, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			log.Logger.Printf("[KAFKA] Error Fetch Message from %s: %v", c.Config.Topic, err)
+			continue
+		}
+
+		err = handleWithRetry(ctx, *c.Producer, c.Config.RetryPolicy, message, c.handle)
+		if err != nil {
+			log.Logger.Printf("[KAFKA] Stopped before handling %s offset %d, it will be redelivered: %v", message.Topic, message.Offset, err)
+			return nil
+		}
+
+		// commit even when shutting down, the message is already handled
+		err = reader.CommitMessages(context.WithoutCancel(ctx), message)
+		if err != nil {
+			log.Logger.Printf("[KAFKA] Error Commit Message from %s: %v", c.Config.Topic, err)
+		}
+	}
+}
+
+func (c *Consumer[T]) handle(ctx context.Context, message kafka.Message) error {
+	var event T
+	envelope, err := kafkaOrder.DecodeMessage(message, &event)
+	if err != nil {
+		return Permanent(fmt.Errorf("decode event message value: %w", err))
+	}
+
+	return c.Handler(kafkaOrder.ContextWithEnvelope(ctx, envelope), event, message)
+}
+
+func (c *Consumer[T]) Close() error {
+	errs := make([]error, 0, len(c.Readers))
+	for _, reader := range c.Readers {
+		errs = append(errs, reader.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// Runtime runs a set of consumers side by side and closes them once they all stopped.
+type Runtime struct {
+	Runners []Runner
+}
+
+func NewRuntime(runners ...Runner) *Runtime {
+	return &Runtime{
+		Runners: runners,
+	}
+}
+
+// Run blocks until ctx is done and every runner drained its in-flight message, then closes them.
+func (r *Runtime) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(r.Runners))
+
+	for index, runner := range r.Runners {
+		wg.Add(1)
+
+		go func(index int, runner Runner) {
+			defer wg.Done()
+
+			if err := runner.Run(ctx); err != nil {
+				errs[index] = fmt.Errorf("%s: %w", runner.Name(), err)
+			}
+		}(index, runner)
+	}
+
+	wg.Wait()
+
+	for _, runner := range r.Runners {
+		if err := runner.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close %s: %w", runner.Name(), err))
+		}
+	}
+
+	log.Logger.Println("[KAFKA] All consumers stopped")
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,141 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"order/infrastructure/constant"
+	"order/kafka/schema"
+	"order/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+)
+
+const ProducerName = "order-service"
+
+// EventVersions is the payload version produced for every event type. Bump it together with a new
+// schema file when a payload changes incompatibly.
+var EventVersions = map[string]int{
+	// version 2 charges total_amount in currency, version 1 had it in the base currency
+	constant.TopicOrderCreated:            2,
+	constant.TopicOrderCancelled:          1,
+	constant.TopicOrderExpired:            1,
+	constant.TopicProductStockUpdate:      1,
+	constant.TopicProductStockRollback:    1,
+	constant.TopicStockReservationConfirm: 1,
+	constant.TopicStockReservationRelease: 1,
+	constant.TopicPaymentSuccess:          1,
+	constant.TopicPaymentFailed:           1,
+}
+
+type envelopeContextKey struct{}
+
+// EncodeEvent wraps payload in a versioned envelope, validating both against their schemas.
+func EncodeEvent(ctx context.Context, eventType string, payload any) ([]byte, error) {
+	version, isExist := EventVersions[eventType]
+	if !isExist {
+		return nil, fmt.Errorf("unknown event type %s", eventType)
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	err = schema.Validate(schema.Name(eventType, version), payloadJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := json.Marshal(models.EventEnvelope{
+		ID:            uuid.New().String(),
+		Type:          eventType,
+		Version:       version,
+		OccurredAt:    time.Now(),
+		Producer:      ProducerName,
+		CorrelationID: CorrelationID(ctx),
+		Payload:       payloadJSON,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = schema.Validate(schema.Name("envelope", 1), value)
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// DecodeMessage validates a consumed message and unmarshals its payload into target. The
+// content-type header selects the serializer. Bare JSON payloads from producers that do not send
+// envelopes yet are accepted as version 1 of the topic's event.
+func DecodeMessage(message kafka.Message, target any) (models.EventEnvelope, error) {
+	serializer, err := SerializerForContentType(contentTypeHeader(message.Headers))
+	if err != nil {
+		return models.EventEnvelope{}, err
+	}
+
+	envelope, err := serializer.Unmarshal(message.Value)
+	if err != nil {
+		return models.EventEnvelope{}, err
+	}
+
+	if envelope.Type == "" {
+		envelope.Type = message.Topic
+		envelope.Version = 1
+	} else {
+		value, err := json.Marshal(envelope)
+		if err != nil {
+			return models.EventEnvelope{}, err
+		}
+
+		err = schema.Validate(schema.Name("envelope", 1), value)
+		if err != nil {
+			return models.EventEnvelope{}, err
+		}
+	}
+
+	name := schema.Name(envelope.Type, envelope.Version)
+	if !schema.Has(name) {
+		return models.EventEnvelope{}, fmt.Errorf("unsupported event %s", name)
+	}
+
+	err = schema.Validate(name, envelope.Payload)
+	if err != nil {
+		return models.EventEnvelope{}, err
+	}
+
+	err = json.Unmarshal(envelope.Payload, target)
+	if err != nil {
+		return models.EventEnvelope{}, err
+	}
+
+	return envelope, nil
+}
+
+func ContextWithEnvelope(ctx context.Context, envelope models.EventEnvelope) context.Context {
+	return context.WithValue(ctx, envelopeContextKey{}, envelope)
+}
+
+func EnvelopeFromContext(ctx context.Context) (models.EventEnvelope, bool) {
+	envelope, ok := ctx.Value(envelopeContextKey{}).(models.EventEnvelope)
+	return envelope, ok
+}
+
+// CorrelationID follows the event being consumed or, for HTTP requests, the request id.
+func CorrelationID(ctx context.Context) string {
+	if envelope, ok := EnvelopeFromContext(ctx); ok {
+		if envelope.CorrelationID != "" {
+			return envelope.CorrelationID
+		}
+
+		return envelope.ID
+	}
+
+	requestID, _ := ctx.Value(constant.ContextKeyRequestID).(string)
+	return requestID
+}
@@ -0,0 +1,223 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"order/infrastructure/constant"
+	"order/models"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestEncodeEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		eventType string
+		payload   any
+		wantErr   string
+	}{
+		{
+			name:      "valid payload",
+			eventType: constant.TopicPaymentSuccess,
+			payload:   models.PaymentUpdateStatusEvent{OrderID: 7, Status: "success"},
+		},
+		{
+			name:      "valid payload with nested items",
+			eventType: constant.TopicProductStockRollback,
+			payload: models.ProductStockUpdateEvent{
+				OrderID:   7,
+				Products:  []models.ProductItem{{ProductID: 11, Qty: 2}},
+				EventTime: time.Now(),
+			},
+		},
+		{
+			name:      "zero value breaking a minimum",
+			eventType: constant.TopicPaymentSuccess,
+			payload:   models.PaymentUpdateStatusEvent{Status: "success"},
+			wantErr:   "$.order_id: 0 is below 1",
+		},
+		{
+			name:      "missing required field",
+			eventType: constant.TopicStockReservationConfirm,
+			payload:   map[string]any{"order_id": 7, "event_time": "2026-10-18T09:30:15Z"},
+			wantErr:   "missing required field reservation_id",
+		},
+		{
+			name:      "wrong type",
+			eventType: constant.TopicStockReservationConfirm,
+			payload:   map[string]any{"order_id": 7, "reservation_id": 12, "event_time": "2026-10-18T09:30:15Z"},
+			wantErr:   "$.reservation_id: expected string, got integer",
+		},
+		{
+			name:      "unknown event type",
+			eventType: "order.shipped",
+			payload:   models.PaymentUpdateStatusEvent{OrderID: 7},
+			wantErr:   "unknown event type order.shipped",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			value, err := EncodeEvent(context.Background(), tc.eventType, tc.payload)
+
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("EncodeEvent() error = %v, want %q", err, tc.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("EncodeEvent() error = %v", err)
+			}
+
+			var envelope models.EventEnvelope
+			err = json.Unmarshal(value, &envelope)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if envelope.Type != tc.eventType || envelope.Version != EventVersions[tc.eventType] || envelope.Producer != ProducerName {
+				t.Errorf("envelope = %s %d %s, want %s %d %s", envelope.Type, envelope.Version, envelope.Producer, tc.eventType, EventVersions[tc.eventType], ProducerName)
+			}
+		})
+	}
+}
+
+func TestDecodeMessage(t *testing.T) {
+	envelope := func(eventType string, version int, payload string) []byte {
+		return []byte(`{"id": "5f0c6f7e", "type": "` + eventType + `", "version": ` + strconv.Itoa(version) +
+			`, "occurred_at": "2026-10-18T09:30:15Z", "producer": "payment-service", "payload": ` + payload + `}`)
+	}
+
+	tests := []struct {
+		name        string
+		topic       string
+		contentType string
+		value       []byte
+		wantOrderID int64
+		wantErr     string
+	}{
+		{
+			name:        "valid envelope",
+			topic:       constant.TopicPaymentSuccess,
+			contentType: ContentTypeJSON,
+			value:       envelope(constant.TopicPaymentSuccess, 1, `{"order_id": 7, "status": "success"}`),
+			wantOrderID: 7,
+		},
+		{
+			name:        "bare payload is read as version 1 of the topic",
+			topic:       constant.TopicPaymentFailed,
+			value:       []byte(`{"order_id": 8, "status": "failed"}`),
+			wantOrderID: 8,
+		},
+		{
+			name:        "missing required field",
+			topic:       constant.TopicPaymentSuccess,
+			contentType: ContentTypeJSON,
+			value:       envelope(constant.TopicPaymentSuccess, 1, `{"status": "success"}`),
+			wantErr:     "missing required field order_id",
+		},
+		{
+			name:        "wrong type",
+			topic:       constant.TopicPaymentSuccess,
+			contentType: ContentTypeJSON,
+			value:       envelope(constant.TopicPaymentSuccess, 1, `{"order_id": "7"}`),
+			wantErr:     "$.order_id: expected integer, got string",
+		},
+		{
+			name:    "bare payload breaking the schema",
+			topic:   constant.TopicPaymentFailed,
+			value:   []byte(`{"order_id": -1}`),
+			wantErr: "$.order_id: -1 is below 1",
+		},
+		{
+			name:        "envelope missing its producer",
+			topic:       constant.TopicPaymentSuccess,
+			contentType: ContentTypeJSON,
+			value:       []byte(`{"id": "5f0c6f7e", "type": "payment.success", "version": 1, "occurred_at": "2026-10-18T09:30:15Z", "payload": {"order_id": 7}}`),
+			wantErr:     "schema envelope.v1",
+		},
+		{
+			name:        "unknown version",
+			topic:       constant.TopicPaymentSuccess,
+			contentType: ContentTypeJSON,
+			value:       envelope(constant.TopicPaymentSuccess, 9, `{"order_id": 7}`),
+			wantErr:     "unsupported event payment.success.v9",
+		},
+		{
+			name:        "unknown event type",
+			topic:       constant.TopicPaymentSuccess,
+			contentType: ContentTypeJSON,
+			value:       envelope("payment.refunded", 1, `{"order_id": 7}`),
+			wantErr:     "unsupported event payment.refunded.v1",
+		},
+		{
+			name:        "unknown content type",
+			topic:       constant.TopicPaymentSuccess,
+			contentType: "application/avro",
+			value:       envelope(constant.TopicPaymentSuccess, 1, `{"order_id": 7}`),
+			wantErr:     "application/avro",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			message := kafka.Message{Topic: tc.topic, Value: tc.value}
+			if tc.contentType != "" {
+				message.Headers = []kafka.Header{{Key: HeaderContentType, Value: []byte(tc.contentType)}}
+			}
+
+			var event models.PaymentUpdateStatusEvent
+			_, err := DecodeMessage(message, &event)
+
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("DecodeMessage() error = %v, want %q", err, tc.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("DecodeMessage() error = %v", err)
+			}
+
+			if event.OrderID != tc.wantOrderID {
+				t.Errorf("order id = %d, want %d", event.OrderID, tc.wantOrderID)
+			}
+		})
+	}
+}
+
+// What EncodeEvent produces is accepted by DecodeMessage.
+func TestEncodedEventsDecode(t *testing.T) {
+	value, err := EncodeEvent(context.Background(), constant.TopicStockReservationRelease, models.StockReservationEvent{
+		OrderID:       7,
+		ReservationID: "rsv-1",
+		Reason:        "payment failed",
+		EventTime:     time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var event models.StockReservationEvent
+	envelope, err := DecodeMessage(kafka.Message{
+		Topic:   constant.TopicStockReservationRelease,
+		Value:   value,
+		Headers: []kafka.Header{{Key: HeaderContentType, Value: []byte(ContentTypeJSON)}},
+	}, &event)
+	if err != nil {
+		t.Fatalf("DecodeMessage() error = %v", err)
+	}
+
+	if envelope.Version != 1 || event.ReservationID != "rsv-1" {
+		t.Errorf("decoded v%d %+v", envelope.Version, event)
+	}
+}
@@ -3,22 +3,35 @@ package kafka
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"order/infrastructure/constant"
 	"order/models"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 )
 
 type KafkaProducer struct {
 	Writer *kafka.Writer
+
+	// Serializers is the format of each topic, topics that are not listed are published as JSON.
+	Serializers map[string]Serializer
 }
 
-func NewKafkaProducer(brokers []string) *KafkaProducer {
+// writerBatchTimeout bounds how long a write waits for more messages, kafka-go waits a second by default.
+const writerBatchTimeout = 10 * time.Millisecond
+
+// NewKafkaProducer partitions by message key, so every event of an order lands on the same partition
+// and is consumed in the order it was published.
+func NewKafkaProducer(brokers []string, serializers map[string]Serializer) *KafkaProducer {
 	return &KafkaProducer{
 		Writer: &kafka.Writer{
-			Addr:     kafka.TCP(brokers...),
-			Balancer: &kafka.LeastBytes{},
+			Addr:         kafka.TCP(brokers...),
+			Balancer:     &kafka.Hash{},
+			BatchTimeout: writerBatchTimeout,
 		},
+		Serializers: serializers,
 	}
 }
 
@@ -26,47 +39,208 @@ func (p *KafkaProducer) Close() error {
 	return p.Writer.Close()
 }
 
-func (p *KafkaProducer) PublishOrderCreated(ctx context.Context, event models.OrderCreatedEvent) error {
-	value, err := json.Marshal(event)
+func OrderMessageKey(orderID int64) string {
+	return fmt.Sprintf("order-%d", orderID)
+}
+
+// NewOutboxMessage encodes an order event so it can be stored in the outbox and relayed later.
+func NewOutboxMessage(ctx context.Context, topic string, orderID int64, event any) (models.OutboxMessage, error) {
+	value, err := EncodeEvent(ctx, topic, event)
 	if err != nil {
-		return err
+		return models.OutboxMessage{}, err
 	}
 
-	msg := kafka.Message{
-		Key:   []byte(fmt.Sprintf("order-%d", event.OrderID)),
-		Value: value,
-		Topic: "order.created",
+	now := time.Now()
+
+	return models.OutboxMessage{
+		Topic:           topic,
+		MessageKey:      OrderMessageKey(orderID),
+		Payload:         string(value),
+		NextAttemptTime: now,
+		CreateTime:      now,
+	}, nil
+}
+
+// NewStockRollbackMessage builds the stock.rollback event returning every product of the order.
+func NewStockRollbackMessage(ctx context.Context, orderID int64, orderDetail models.OrderDetail) (models.OutboxMessage, error) {
+	var products []models.CheckoutItem
+	err := json.Unmarshal([]byte(orderDetail.Products), &products)
+	if err != nil {
+		return models.OutboxMessage{}, err
 	}
 
-	return p.Writer.WriteMessages(ctx, msg)
+	return NewOutboxMessage(ctx, constant.TopicProductStockRollback, orderID, models.ProductStockUpdateEvent{
+		OrderID:   orderID,
+		Products:  ProductItemsFromCheckoutItems(products),
+		EventTime: time.Now(),
+	})
 }
 
-func (p *KafkaProducer) PublishProductStockUpdate(ctx context.Context, event models.ProductStockUpdateEvent) error {
-	value, err := json.Marshal(event)
+// NewStockReturnMessage builds the event giving the stock of an order that will not be paid back to
+// product service: the release of its reservation, or stock.rollback for an order placed before
+// checkout reserved stock.
+func NewStockReturnMessage(ctx context.Context, order models.Order, orderDetail models.OrderDetail, reason string) (models.OutboxMessage, error) {
+	if order.StockReservationID == "" {
+		return NewStockRollbackMessage(ctx, order.ID, orderDetail)
+	}
+
+	return NewOutboxMessage(ctx, constant.TopicStockReservationRelease, order.ID, models.StockReservationEvent{
+		OrderID:       order.ID,
+		ReservationID: order.StockReservationID,
+		Reason:        reason,
+		EventTime:     time.Now(),
+	})
+}
+
+// NewStockConfirmMessage builds the event turning the reservation of a paid order into a sale.
+func NewStockConfirmMessage(ctx context.Context, order models.Order) (models.OutboxMessage, error) {
+	return NewOutboxMessage(ctx, constant.TopicStockReservationConfirm, order.ID, models.StockReservationEvent{
+		OrderID:       order.ID,
+		ReservationID: order.StockReservationID,
+		Reason:        "payment success",
+		EventTime:     time.Now(),
+	})
+}
+
+func ProductItemsFromCheckoutItems(items []models.CheckoutItem) []models.ProductItem {
+	result := make([]models.ProductItem, len(items))
+
+	for index, item := range items {
+		result[index] = models.ProductItem{
+			ProductID: item.ProductID,
+			Qty:       item.Quantity,
+		}
+	}
+
+	return result
+}
+
+// Publish sends a JSON event envelope, converted to the serializer configured for the topic.
+func (p *KafkaProducer) Publish(ctx context.Context, topic string, key string, value []byte) error {
+	msg, err := p.message(topic, key, value)
 	if err != nil {
 		return err
 	}
 
-	msg := kafka.Message{
-		Key:   []byte(fmt.Sprintf("order-%d", event.OrderID)),
-		Value: value,
-		Topic: "stock.update",
+	return p.Writer.WriteMessages(ctx, msg)
+}
+
+// message builds the kafka message of a JSON event envelope in the serializer of the topic.
+func (p *KafkaProducer) message(topic string, key string, value []byte) (kafka.Message, error) {
+	serializer, isExist := p.Serializers[topic]
+	if !isExist {
+		serializer = JSONSerializer{}
+	}
+
+	if serializer.ContentType() != ContentTypeJSON {
+		var envelope models.EventEnvelope
+		err := json.Unmarshal(value, &envelope)
+		if err != nil {
+			return kafka.Message{}, err
+		}
+
+		value, err = serializer.Marshal(envelope)
+		if err != nil {
+			return kafka.Message{}, err
+		}
 	}
 
+	return kafka.Message{
+		Key:   []byte(key),
+		Value: value,
+		Topic: topic,
+		Headers: []kafka.Header{
+			{Key: HeaderContentType, Value: []byte(serializer.ContentType())},
+		},
+	}, nil
+}
+
+func (p *KafkaProducer) PublishMessage(ctx context.Context, msg kafka.Message) error {
 	return p.Writer.WriteMessages(ctx, msg)
 }
 
-func (p *KafkaProducer) PublishProductStockRollback(ctx context.Context, event models.ProductStockUpdateEvent) error {
-	value, err := json.Marshal(event)
+func (p *KafkaProducer) PublishOutboxMessage(ctx context.Context, message models.OutboxMessage) error {
+	return p.Publish(ctx, message.Topic, message.MessageKey, []byte(message.Payload))
+}
+
+// PublishOutboxMessages writes the messages in one call. Kafka reports every message on its own, so
+// some can be written while others fail, and messages of one key on different topics are not written
+// in order; a caller that needs a key in order passes at most one message of it per call. The result
+// holds the error of the message at the same index, nil for a message that was written, and is nil
+// itself when every message was.
+func (p *KafkaProducer) PublishOutboxMessages(ctx context.Context, messages []models.OutboxMessage) []error {
+	var results []error
+	fail := func(index int, err error) {
+		if results == nil {
+			results = make([]error, len(messages))
+		}
+
+		results[index] = err
+	}
+
+	msgs := make([]kafka.Message, 0, len(messages))
+	indexes := make([]int, 0, len(messages))
+	for index, message := range messages {
+		msg, err := p.message(message.Topic, message.MessageKey, []byte(message.Payload))
+		if err != nil {
+			fail(index, err)
+			continue
+		}
+
+		msgs = append(msgs, msg)
+		indexes = append(indexes, index)
+	}
+
+	if len(msgs) == 0 {
+		return results
+	}
+
+	err := p.Writer.WriteMessages(ctx, msgs...)
+	if err == nil {
+		return results
+	}
+
+	var writeErrors kafka.WriteErrors
+	if errors.As(err, &writeErrors) && len(writeErrors) == len(msgs) {
+		for position, writeErr := range writeErrors {
+			if writeErr != nil {
+				fail(indexes[position], writeErr)
+			}
+		}
+
+		return results
+	}
+
+	for _, index := range indexes {
+		fail(index, err)
+	}
+
+	return results
+}
+
+func (p *KafkaProducer) PublishOrderCreated(ctx context.Context, event models.OrderCreatedEvent) error {
+	value, err := EncodeEvent(ctx, constant.TopicOrderCreated, event)
 	if err != nil {
 		return err
 	}
 
-	msg := kafka.Message{
-		Key:   []byte(fmt.Sprintf("order-%d", event.OrderID)),
-		Value: value,
-		Topic: "stock.rollback",
+	return p.Publish(ctx, constant.TopicOrderCreated, OrderMessageKey(event.OrderID), value)
+}
+
+func (p *KafkaProducer) PublishProductStockUpdate(ctx context.Context, event models.ProductStockUpdateEvent) error {
+	value, err := EncodeEvent(ctx, constant.TopicProductStockUpdate, event)
+	if err != nil {
+		return err
 	}
 
-	return p.Writer.WriteMessages(ctx, msg)
+	return p.Publish(ctx, constant.TopicProductStockUpdate, OrderMessageKey(event.OrderID), value)
+}
+
+func (p *KafkaProducer) PublishProductStockRollback(ctx context.Context, event models.ProductStockUpdateEvent) error {
+	value, err := EncodeEvent(ctx, constant.TopicProductStockRollback, event)
+	if err != nil {
+		return err
+	}
+
+	return p.Publish(ctx, constant.TopicProductStockRollback, OrderMessageKey(event.OrderID), value)
 }
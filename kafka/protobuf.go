@@ -0,0 +1,543 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"order/infrastructure/constant"
+	"order/infrastructure/money"
+	"order/models"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ProtobufSerializer writes the messages defined in proto/order_events.proto. The wire format is
+// encoded by hand so the service does not need generated code for a handful of flat messages.
+type ProtobufSerializer struct{}
+
+// protoCodec converts the JSON payload of an envelope to its protobuf message and back.
+type protoCodec struct {
+	encode func(payload json.RawMessage) ([]byte, error)
+	decode func(value []byte) (any, error)
+}
+
+var protoCodecs = map[string]protoCodec{
+	constant.TopicOrderCreated:            {encode: encodeOrderCreated, decode: decodeOrderCreated},
+	constant.TopicProductStockUpdate:      {encode: encodeProductStockUpdate, decode: decodeProductStockUpdate},
+	constant.TopicProductStockRollback:    {encode: encodeProductStockUpdate, decode: decodeProductStockUpdate},
+	constant.TopicStockReservationConfirm: {encode: encodeStockReservation, decode: decodeStockReservation},
+	constant.TopicStockReservationRelease: {encode: encodeStockReservation, decode: decodeStockReservation},
+	constant.TopicPaymentSuccess:          {encode: encodePaymentUpdateStatus, decode: decodePaymentUpdateStatus},
+	constant.TopicPaymentFailed:           {encode: encodePaymentUpdateStatus, decode: decodePaymentUpdateStatus},
+}
+
+func (ProtobufSerializer) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+func (ProtobufSerializer) Marshal(envelope models.EventEnvelope) ([]byte, error) {
+	codec, isExist := protoCodecs[envelope.Type]
+	if !isExist {
+		return nil, fmt.Errorf("event %s has no protobuf definition", envelope.Type)
+	}
+
+	payload, err := codec.encode(envelope.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	b = appendProtoString(b, 1, envelope.ID)
+	b = appendProtoString(b, 2, envelope.Type)
+	b = appendProtoInt(b, 3, int64(envelope.Version))
+	b = appendProtoTimestamp(b, 4, envelope.OccurredAt)
+	b = appendProtoString(b, 5, envelope.Producer)
+	b = appendProtoString(b, 6, envelope.CorrelationID)
+	b = appendProtoBytes(b, 7, payload)
+
+	return b, nil
+}
+
+// Unmarshal decodes the envelope and turns its payload back into JSON, so schema validation and
+// decoding into the event type work the same for both formats.
+func (ProtobufSerializer) Unmarshal(value []byte) (models.EventEnvelope, error) {
+	var envelope models.EventEnvelope
+	var payload []byte
+
+	err := consumeProtoFields(value, func(field protoField) error {
+		var err error
+
+		switch field.Number {
+		case 1:
+			envelope.ID = string(field.Bytes)
+		case 2:
+			envelope.Type = string(field.Bytes)
+		case 3:
+			envelope.Version = int(int32(field.Varint))
+		case 4:
+			envelope.OccurredAt, err = consumeProtoTimestamp(field.Bytes)
+		case 5:
+			envelope.Producer = string(field.Bytes)
+		case 6:
+			envelope.CorrelationID = string(field.Bytes)
+		case 7:
+			payload = field.Bytes
+		}
+
+		return err
+	})
+	if err != nil {
+		return models.EventEnvelope{}, err
+	}
+
+	codec, isExist := protoCodecs[envelope.Type]
+	if !isExist {
+		return models.EventEnvelope{}, fmt.Errorf("event %s has no protobuf definition", envelope.Type)
+	}
+
+	event, err := codec.decode(payload)
+	if err != nil {
+		return models.EventEnvelope{}, err
+	}
+
+	envelope.Payload, err = json.Marshal(event)
+	if err != nil {
+		return models.EventEnvelope{}, err
+	}
+
+	return envelope, nil
+}
+
+func encodeOrderCreated(payload json.RawMessage) ([]byte, error) {
+	var event models.OrderCreatedEvent
+	err := json.Unmarshal(payload, &event)
+	if err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	b = appendProtoInt(b, 1, event.OrderID)
+	b = appendProtoInt(b, 2, event.UserID)
+	b = appendProtoDouble(b, 3, event.TotalAmount.Float64())
+	b = appendProtoInt(b, 4, int64(event.TotalQty))
+	b = appendProtoString(b, 5, event.PaymentMethod)
+	b = appendProtoString(b, 6, event.ShippingAddress)
+	b = appendProtoInt(b, 7, event.TotalAmount.Minor())
+	b = appendProtoString(b, 8, event.Currency)
+	b = appendProtoInt(b, 9, event.BaseAmount.Minor())
+	b = appendProtoString(b, 10, event.BaseCurrency)
+	b = appendProtoString(b, 11, event.ExchangeRate.String())
+	b = appendProtoInt(b, 12, event.Subtotal.Minor())
+	b = appendProtoInt(b, 13, event.DiscountAmount.Minor())
+	b = appendProtoInt(b, 14, event.TaxAmount.Minor())
+	b = appendProtoString(b, 15, event.TaxMode)
+	for _, line := range event.Items {
+		var item []byte
+		item = appendProtoInt(item, 1, line.ProductID)
+		item = appendProtoInt(item, 2, int64(line.Quantity))
+		item = appendProtoInt(item, 3, line.UnitPrice.Minor())
+		item = appendProtoInt(item, 4, line.LineTotal.Minor())
+		item = appendProtoInt(item, 5, line.DiscountAmount.Minor())
+		item = appendProtoInt(item, 6, line.TaxRateBps)
+		item = appendProtoInt(item, 7, line.TaxAmount.Minor())
+
+		b = protowire.AppendTag(b, 16, protowire.BytesType)
+		b = protowire.AppendBytes(b, item)
+	}
+	b = appendProtoString(b, 17, event.ShippingMethod)
+	b = appendProtoString(b, 18, event.ShippingCarrier)
+	b = appendProtoInt(b, 19, event.ShippingFee.Minor())
+	if address := event.ShippingAddressDetail; address != nil {
+		var detail []byte
+		detail = appendProtoInt(detail, 1, address.ID)
+		detail = appendProtoString(detail, 2, address.Label)
+		detail = appendProtoString(detail, 3, address.Recipient)
+		detail = appendProtoString(detail, 4, address.Phone)
+		detail = appendProtoString(detail, 5, address.Line1)
+		detail = appendProtoString(detail, 6, address.Line2)
+		detail = appendProtoString(detail, 7, address.City)
+		detail = appendProtoString(detail, 8, address.Region)
+		detail = appendProtoString(detail, 9, address.PostalCode)
+		detail = appendProtoString(detail, 10, address.Country)
+
+		b = protowire.AppendTag(b, 20, protowire.BytesType)
+		b = protowire.AppendBytes(b, detail)
+	}
+
+	return b, nil
+}
+
+func decodeOrderCreated(value []byte) (any, error) {
+	var event models.OrderCreatedEvent
+	var totalAmount float64
+	var hasTotalAmountMinor bool
+
+	err := consumeProtoFields(value, func(field protoField) error {
+		switch field.Number {
+		case 1:
+			event.OrderID = int64(field.Varint)
+		case 2:
+			event.UserID = int64(field.Varint)
+		case 3:
+			totalAmount = math.Float64frombits(field.Fixed64)
+		case 4:
+			event.TotalQty = int(int32(field.Varint))
+		case 5:
+			event.PaymentMethod = string(field.Bytes)
+		case 6:
+			event.ShippingAddress = string(field.Bytes)
+		case 7:
+			event.TotalAmount = money.FromMinor(int64(field.Varint))
+			hasTotalAmountMinor = true
+		case 8:
+			event.Currency = string(field.Bytes)
+		case 9:
+			event.BaseAmount = money.FromMinor(int64(field.Varint))
+		case 10:
+			event.BaseCurrency = string(field.Bytes)
+		case 11:
+			rate, err := money.ParseRate(string(field.Bytes))
+			if err != nil {
+				return err
+			}
+
+			event.ExchangeRate = rate
+		case 12:
+			event.Subtotal = money.FromMinor(int64(field.Varint))
+		case 13:
+			event.DiscountAmount = money.FromMinor(int64(field.Varint))
+		case 14:
+			event.TaxAmount = money.FromMinor(int64(field.Varint))
+		case 15:
+			event.TaxMode = string(field.Bytes)
+		case 16:
+			var line models.OrderLine
+			err := consumeProtoFields(field.Bytes, func(field protoField) error {
+				switch field.Number {
+				case 1:
+					line.ProductID = int64(field.Varint)
+				case 2:
+					line.Quantity = int(int32(field.Varint))
+				case 3:
+					line.UnitPrice = money.FromMinor(int64(field.Varint))
+				case 4:
+					line.LineTotal = money.FromMinor(int64(field.Varint))
+				case 5:
+					line.DiscountAmount = money.FromMinor(int64(field.Varint))
+				case 6:
+					line.TaxRateBps = int64(field.Varint)
+				case 7:
+					line.TaxAmount = money.FromMinor(int64(field.Varint))
+				}
+
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			event.Items = append(event.Items, line)
+		case 17:
+			event.ShippingMethod = string(field.Bytes)
+		case 18:
+			event.ShippingCarrier = string(field.Bytes)
+		case 19:
+			event.ShippingFee = money.FromMinor(int64(field.Varint))
+		case 20:
+			var address models.Address
+			err := consumeProtoFields(field.Bytes, func(field protoField) error {
+				switch field.Number {
+				case 1:
+					address.ID = int64(field.Varint)
+				case 2:
+					address.Label = string(field.Bytes)
+				case 3:
+					address.Recipient = string(field.Bytes)
+				case 4:
+					address.Phone = string(field.Bytes)
+				case 5:
+					address.Line1 = string(field.Bytes)
+				case 6:
+					address.Line2 = string(field.Bytes)
+				case 7:
+					address.City = string(field.Bytes)
+				case 8:
+					address.Region = string(field.Bytes)
+				case 9:
+					address.PostalCode = string(field.Bytes)
+				case 10:
+					address.Country = string(field.Bytes)
+				}
+
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			event.ShippingAddressDetail = &address
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// messages written before total_amount_minor existed only carry the double
+	if !hasTotalAmountMinor {
+		event.TotalAmount, err = money.FromFloat(totalAmount)
+	}
+
+	return event, err
+}
+
+// encodeProductStockUpdate serves both ProductStockUpdate and StockRollback, they share field numbers.
+func encodeProductStockUpdate(payload json.RawMessage) ([]byte, error) {
+	var event models.ProductStockUpdateEvent
+	err := json.Unmarshal(payload, &event)
+	if err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	b = appendProtoInt(b, 1, event.OrderID)
+	for _, product := range event.Products {
+		var item []byte
+		item = appendProtoInt(item, 1, product.ProductID)
+		item = appendProtoInt(item, 2, int64(product.Qty))
+
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, item)
+	}
+	b = appendProtoTimestamp(b, 3, event.EventTime)
+
+	return b, nil
+}
+
+func decodeProductStockUpdate(value []byte) (any, error) {
+	event := models.ProductStockUpdateEvent{
+		Products: []models.ProductItem{},
+	}
+
+	err := consumeProtoFields(value, func(field protoField) error {
+		var err error
+
+		switch field.Number {
+		case 1:
+			event.OrderID = int64(field.Varint)
+		case 2:
+			var product models.ProductItem
+			err = consumeProtoFields(field.Bytes, func(field protoField) error {
+				switch field.Number {
+				case 1:
+					product.ProductID = int64(field.Varint)
+				case 2:
+					product.Qty = int(int32(field.Varint))
+				}
+
+				return nil
+			})
+
+			event.Products = append(event.Products, product)
+		case 3:
+			event.EventTime, err = consumeProtoTimestamp(field.Bytes)
+		}
+
+		return err
+	})
+
+	return event, err
+}
+
+func encodeStockReservation(payload json.RawMessage) ([]byte, error) {
+	var event models.StockReservationEvent
+	err := json.Unmarshal(payload, &event)
+	if err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	b = appendProtoInt(b, 1, event.OrderID)
+	b = appendProtoString(b, 2, event.ReservationID)
+	b = appendProtoString(b, 3, event.Reason)
+	b = appendProtoTimestamp(b, 4, event.EventTime)
+
+	return b, nil
+}
+
+func decodeStockReservation(value []byte) (any, error) {
+	var event models.StockReservationEvent
+
+	err := consumeProtoFields(value, func(field protoField) error {
+		var err error
+
+		switch field.Number {
+		case 1:
+			event.OrderID = int64(field.Varint)
+		case 2:
+			event.ReservationID = string(field.Bytes)
+		case 3:
+			event.Reason = string(field.Bytes)
+		case 4:
+			event.EventTime, err = consumeProtoTimestamp(field.Bytes)
+		}
+
+		return err
+	})
+
+	return event, err
+}
+
+func encodePaymentUpdateStatus(payload json.RawMessage) ([]byte, error) {
+	var event models.PaymentUpdateStatusEvent
+	err := json.Unmarshal(payload, &event)
+	if err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	b = appendProtoInt(b, 1, event.OrderID)
+	b = appendProtoString(b, 2, event.Status)
+
+	return b, nil
+}
+
+func decodePaymentUpdateStatus(value []byte) (any, error) {
+	var event models.PaymentUpdateStatusEvent
+
+	err := consumeProtoFields(value, func(field protoField) error {
+		switch field.Number {
+		case 1:
+			event.OrderID = int64(field.Varint)
+		case 2:
+			event.Status = string(field.Bytes)
+		}
+
+		return nil
+	})
+
+	return event, err
+}
+
+// Zero values are skipped like proto3 does for scalar fields.
+
+func appendProtoInt(b []byte, number protowire.Number, value int64) []byte {
+	if value == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(value))
+}
+
+func appendProtoDouble(b []byte, number protowire.Number, value float64) []byte {
+	if value == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(value))
+}
+
+func appendProtoString(b []byte, number protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func appendProtoBytes(b []byte, number protowire.Number, value []byte) []byte {
+	if len(value) == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendBytes(b, value)
+}
+
+// appendProtoTimestamp writes a google.protobuf.Timestamp.
+func appendProtoTimestamp(b []byte, number protowire.Number, value time.Time) []byte {
+	if value.IsZero() {
+		return b
+	}
+
+	var timestamp []byte
+	timestamp = appendProtoInt(timestamp, 1, value.Unix())
+	timestamp = appendProtoInt(timestamp, 2, int64(value.Nanosecond()))
+
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendBytes(b, timestamp)
+}
+
+func consumeProtoTimestamp(value []byte) (time.Time, error) {
+	var seconds, nanos int64
+
+	err := consumeProtoFields(value, func(field protoField) error {
+		switch field.Number {
+		case 1:
+			seconds = int64(field.Varint)
+		case 2:
+			nanos = int64(int32(field.Varint))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(seconds, nanos).UTC(), nil
+}
+
+type protoField struct {
+	Number  protowire.Number
+	Varint  uint64
+	Fixed64 uint64
+	Bytes   []byte
+}
+
+// consumeProtoFields calls fn for every field of a message. Unknown wire types are skipped so older
+// consumers keep working when fields are added.
+func consumeProtoFields(b []byte, fn func(field protoField) error) error {
+	for len(b) > 0 {
+		number, wireType, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		field := protoField{Number: number}
+
+		switch wireType {
+		case protowire.VarintType:
+			field.Varint, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			field.Fixed64, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			field.Bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(number, wireType, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		err := fn(field)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
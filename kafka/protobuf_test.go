@@ -0,0 +1,411 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"order/infrastructure/constant"
+	"order/infrastructure/money"
+	"order/models"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// The hand written codecs are checked against proto/order_events.proto itself: the file is compiled
+// when the tests run and the wire format is read and written with dynamic messages of it.
+
+var compileProto = sync.OnceValues(func() (protoreflect.FileDescriptor, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: []string{"proto"}}),
+	}
+
+	files, err := compiler.Compile(context.Background(), "order_events.proto")
+	if err != nil {
+		return nil, err
+	}
+
+	return files[0], nil
+})
+
+func newProtoMessage(t *testing.T, name string) *dynamicpb.Message {
+	t.Helper()
+
+	file, err := compileProto()
+	if err != nil {
+		t.Fatalf("compile order_events.proto: %v", err)
+	}
+
+	descriptor := file.Messages().ByName(protoreflect.Name(name))
+	if descriptor == nil {
+		t.Fatalf("order_events.proto has no message %s", name)
+	}
+
+	return dynamicpb.NewMessage(descriptor)
+}
+
+var protoEventTime = time.Date(2026, 10, 18, 9, 30, 15, 250000000, time.UTC)
+
+var protoCases = []struct {
+	name    string
+	topic   string
+	message string
+	event   any
+	// golden is the message expected on the wire, in the protobuf JSON mapping
+	golden string
+}{
+	{
+		name:    "order created",
+		topic:   constant.TopicOrderCreated,
+		message: "OrderCreated",
+		event: models.OrderCreatedEvent{
+			OrderID:         7,
+			UserID:          42,
+			Subtotal:        money.FromMinor(3000000),
+			DiscountAmount:  money.FromMinor(500000),
+			TaxAmount:       money.FromMinor(247748),
+			TaxMode:         "inclusive",
+			ShippingMethod:  "express",
+			ShippingCarrier: "JNE",
+			ShippingFee:     money.FromMinor(1800000),
+			TotalAmount:     money.FromMinor(4300050),
+			Currency:        "USD",
+			BaseAmount:      money.FromMinor(69875812),
+			BaseCurrency:    "IDR",
+			ExchangeRate:    mustParseRate("16250.5"),
+			TotalQty:        3,
+			PaymentMethod:   "credit_card",
+			ShippingAddress: "Jl. Sudirman 1",
+			ShippingAddressDetail: &models.Address{
+				ID:         5,
+				Label:      "home",
+				Recipient:  "Budi",
+				Phone:      "+628123",
+				Line1:      "Jl. Sudirman 1",
+				City:       "Jakarta",
+				Region:     "JK",
+				PostalCode: "10220",
+				Country:    "ID",
+			},
+			Items: []models.OrderLine{
+				{ProductID: 11, Quantity: 2, UnitPrice: money.FromMinor(1000000), LineTotal: money.FromMinor(2000000), DiscountAmount: money.FromMinor(333333), TaxRateBps: 1100, TaxAmount: money.FromMinor(165165)},
+				{ProductID: 12, Quantity: 1, UnitPrice: money.FromMinor(1000000), LineTotal: money.FromMinor(1000000), DiscountAmount: money.FromMinor(166667), TaxAmount: money.FromMinor(82583)},
+			},
+		},
+		golden: `{
+			"orderId": "7",
+			"userId": "42",
+			"totalAmount": 43000.5,
+			"totalQty": 3,
+			"paymentMethod": "credit_card",
+			"shippingAddress": "Jl. Sudirman 1",
+			"totalAmountMinor": "4300050",
+			"currency": "USD",
+			"baseAmountMinor": "69875812",
+			"baseCurrency": "IDR",
+			"exchangeRate": "16250.5",
+			"subtotalMinor": "3000000",
+			"discountAmountMinor": "500000",
+			"taxAmountMinor": "247748",
+			"taxMode": "inclusive",
+			"items": [
+				{"productId": "11", "quantity": 2, "unitPriceMinor": "1000000", "lineTotalMinor": "2000000", "discountAmountMinor": "333333", "taxRateBps": "1100", "taxAmountMinor": "165165"},
+				{"productId": "12", "quantity": 1, "unitPriceMinor": "1000000", "lineTotalMinor": "1000000", "discountAmountMinor": "166667", "taxAmountMinor": "82583"}
+			],
+			"shippingMethod": "express",
+			"shippingCarrier": "JNE",
+			"shippingFeeMinor": "1800000",
+			"shippingAddressDetail": {
+				"id": "5",
+				"label": "home",
+				"recipient": "Budi",
+				"phone": "+628123",
+				"line1": "Jl. Sudirman 1",
+				"city": "Jakarta",
+				"region": "JK",
+				"postalCode": "10220",
+				"country": "ID"
+			}
+		}`,
+	},
+	{
+		name:    "stock update",
+		topic:   constant.TopicProductStockUpdate,
+		message: "ProductStockUpdate",
+		event: models.ProductStockUpdateEvent{
+			OrderID:   7,
+			Products:  []models.ProductItem{{ProductID: 11, Qty: 2}, {ProductID: 12, Qty: 1}},
+			EventTime: protoEventTime,
+		},
+		golden: `{
+			"orderId": "7",
+			"products": [{"productId": "11", "quantity": 2}, {"productId": "12", "quantity": 1}],
+			"eventTime": "2026-10-18T09:30:15.250Z"
+		}`,
+	},
+	{
+		name:    "stock rollback",
+		topic:   constant.TopicProductStockRollback,
+		message: "StockRollback",
+		event: models.ProductStockUpdateEvent{
+			OrderID:   7,
+			Products:  []models.ProductItem{{ProductID: 11, Qty: 2}},
+			EventTime: protoEventTime,
+		},
+		golden: `{
+			"orderId": "7",
+			"products": [{"productId": "11", "quantity": 2}],
+			"eventTime": "2026-10-18T09:30:15.250Z"
+		}`,
+	},
+	{
+		name:    "stock reservation confirm",
+		topic:   constant.TopicStockReservationConfirm,
+		message: "StockReservation",
+		event: models.StockReservationEvent{
+			OrderID:       7,
+			ReservationID: "rsv-1",
+			Reason:        "payment success",
+			EventTime:     protoEventTime,
+		},
+		golden: `{
+			"orderId": "7",
+			"reservationId": "rsv-1",
+			"reason": "payment success",
+			"eventTime": "2026-10-18T09:30:15.250Z"
+		}`,
+	},
+	{
+		name:    "stock reservation release",
+		topic:   constant.TopicStockReservationRelease,
+		message: "StockReservation",
+		event: models.StockReservationEvent{
+			OrderID:       7,
+			ReservationID: "rsv-1",
+			Reason:        "payment window elapsed",
+			EventTime:     protoEventTime,
+		},
+		golden: `{
+			"orderId": "7",
+			"reservationId": "rsv-1",
+			"reason": "payment window elapsed",
+			"eventTime": "2026-10-18T09:30:15.250Z"
+		}`,
+	},
+	{
+		name:    "payment success",
+		topic:   constant.TopicPaymentSuccess,
+		message: "PaymentUpdateStatus",
+		event:   models.PaymentUpdateStatusEvent{OrderID: 7, Status: "success"},
+		golden:  `{"orderId": "7", "status": "success"}`,
+	},
+	{
+		name:    "payment failed",
+		topic:   constant.TopicPaymentFailed,
+		message: "PaymentUpdateStatus",
+		event:   models.PaymentUpdateStatusEvent{OrderID: 7, Status: "failed"},
+		golden:  `{"orderId": "7", "status": "failed"}`,
+	},
+}
+
+func mustParseRate(value string) money.Rate {
+	rate, err := money.ParseRate(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return rate
+}
+
+func TestProtoCasesCoverEveryCodec(t *testing.T) {
+	covered := map[string]bool{}
+	for _, tc := range protoCases {
+		covered[tc.topic] = true
+	}
+
+	for topic := range protoCodecs {
+		if !covered[topic] {
+			t.Errorf("no protobuf test case for %s", topic)
+		}
+	}
+}
+
+func TestProtoEncodeMatchesSchema(t *testing.T) {
+	for _, tc := range protoCases {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, err := json.Marshal(tc.event)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			value, err := protoCodecs[tc.topic].encode(payload)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+
+			message := newProtoMessage(t, tc.message)
+			err = proto.Unmarshal(value, message)
+			if err != nil {
+				t.Fatalf("wire format does not parse as %s: %v", tc.message, err)
+			}
+
+			// a field written with a number or wire type the message does not declare ends up unknown
+			assertNoUnknownFields(t, message)
+
+			got, err := protojson.Marshal(message)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertSameJSON(t, got, []byte(tc.golden))
+		})
+	}
+}
+
+func TestProtoDecodeMatchesSchema(t *testing.T) {
+	for _, tc := range protoCases {
+		t.Run(tc.name, func(t *testing.T) {
+			message := newProtoMessage(t, tc.message)
+			err := protojson.Unmarshal([]byte(tc.golden), message)
+			if err != nil {
+				t.Fatalf("golden: %v", err)
+			}
+
+			value, err := proto.Marshal(message)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			event, err := protoCodecs[tc.topic].decode(value)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+
+			got, err := json.Marshal(event)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := json.Marshal(tc.event)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertSameJSON(t, got, want)
+		})
+	}
+}
+
+func TestProtobufSerializerRoundTrip(t *testing.T) {
+	for _, tc := range protoCases {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, err := json.Marshal(tc.event)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			envelope := models.EventEnvelope{
+				ID:            "5f0c6f7e-2b51-4a8e-9b57-3d5f0f6b1c2a",
+				Type:          tc.topic,
+				Version:       EventVersions[tc.topic],
+				OccurredAt:    protoEventTime,
+				Producer:      ProducerName,
+				CorrelationID: "req-1",
+				Payload:       payload,
+			}
+
+			value, err := ProtobufSerializer{}.Marshal(envelope)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+
+			wire := newProtoMessage(t, "Envelope")
+			err = proto.Unmarshal(value, wire)
+			if err != nil {
+				t.Fatalf("envelope does not parse: %v", err)
+			}
+
+			assertNoUnknownFields(t, wire)
+
+			got, err := ProtobufSerializer{}.Unmarshal(value)
+			if err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+
+			assertSameJSON(t, got.Payload, payload)
+
+			got.Payload, envelope.Payload = nil, nil
+			if !reflect.DeepEqual(got, envelope) {
+				t.Errorf("envelope = %+v, want %+v", got, envelope)
+			}
+		})
+	}
+}
+
+// Messages written before total_amount_minor existed only carry the double.
+func TestProtoDecodeLegacyOrderCreated(t *testing.T) {
+	message := newProtoMessage(t, "OrderCreated")
+	err := protojson.Unmarshal([]byte(`{"orderId": "7", "userId": "42", "totalAmount": 150000.5, "totalQty": 1}`), message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := proto.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := decodeOrderCreated(value)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if got := event.(models.OrderCreatedEvent).TotalAmount; got != money.FromMinor(15000050) {
+		t.Errorf("total amount = %s, want 150000.50", got)
+	}
+}
+
+func assertNoUnknownFields(t *testing.T, message protoreflect.Message) {
+	t.Helper()
+
+	if unknown := message.GetUnknown(); len(unknown) > 0 {
+		t.Errorf("%s has %d bytes of unknown fields", message.Descriptor().FullName(), len(unknown))
+	}
+
+	message.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		switch {
+		case field.IsList() && field.Message() != nil:
+			list := value.List()
+			for index := 0; index < list.Len(); index++ {
+				assertNoUnknownFields(t, list.Get(index).Message())
+			}
+		case field.Message() != nil && !field.IsMap():
+			assertNoUnknownFields(t, value.Message())
+		}
+
+		return true
+	})
+}
+
+func assertSameJSON(t *testing.T, got []byte, want []byte) {
+	t.Helper()
+
+	var gotValue, wantValue any
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("got invalid JSON %s: %v", got, err)
+	}
+
+	if err := json.Unmarshal(want, &wantValue); err != nil {
+		t.Fatalf("want invalid JSON %s: %v", want, err)
+	}
+
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
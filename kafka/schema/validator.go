@@ -0,0 +1,214 @@
+package schema
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:embed *.json
+var files embed.FS
+
+// Schema is the subset of JSON Schema the events use: type, required, properties,
+// additionalProperties, items, enum, minimum, minLength and the date-time format.
+type Schema struct {
+	ID                   string             `json:"$id"`
+	Type                 any                `json:"type"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Enum                 []any              `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	MinLength            *int               `json:"minLength"`
+	Format               string             `json:"format"`
+}
+
+var schemas = map[string]*Schema{}
+
+func init() {
+	entries, err := files.ReadDir(".")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, entry := range entries {
+		raw, err := files.ReadFile(entry.Name())
+		if err != nil {
+			panic(err)
+		}
+
+		var schema Schema
+		if err := json.Unmarshal(raw, &schema); err != nil {
+			panic(fmt.Sprintf("schema %s: %v", entry.Name(), err))
+		}
+
+		schemas[strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))] = &schema
+	}
+}
+
+// Name is the schema file of an event type and version, e.g. order.created.v1.
+func Name(eventType string, version int) string {
+	return fmt.Sprintf("%s.v%d", eventType, version)
+}
+
+func Has(name string) bool {
+	_, isExist := schemas[name]
+	return isExist
+}
+
+// Validate checks a JSON document against the named schema.
+func Validate(name string, document []byte) error {
+	schema, isExist := schemas[name]
+	if !isExist {
+		return fmt.Errorf("unknown schema %s", name)
+	}
+
+	var value any
+	decoder := json.NewDecoder(strings.NewReader(string(document)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("schema %s: %w", name, err)
+	}
+
+	if err := schema.validate("$", value); err != nil {
+		return fmt.Errorf("schema %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func (s *Schema) validate(at string, value any) error {
+	if err := s.validateType(at, value); err != nil {
+		return err
+	}
+
+	if len(s.Enum) > 0 && !s.inEnum(value) {
+		return fmt.Errorf("%s: %v is not one of %v", at, value, s.Enum)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		return s.validateObject(at, v)
+	case []any:
+		if s.Items != nil {
+			for index, item := range v {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", at, index), item); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		if s.MinLength != nil && len(v) < *s.MinLength {
+			return fmt.Errorf("%s: shorter than %d", at, *s.MinLength)
+		}
+
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+				return fmt.Errorf("%s: %q is not a date-time", at, v)
+			}
+		}
+	case json.Number:
+		number, _ := v.Float64()
+		if s.Minimum != nil && number < *s.Minimum {
+			return fmt.Errorf("%s: %v is below %v", at, number, *s.Minimum)
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) validateObject(at string, object map[string]any) error {
+	for _, field := range s.Required {
+		if _, isExist := object[field]; !isExist {
+			return fmt.Errorf("%s: missing required field %s", at, field)
+		}
+	}
+
+	fields := make([]string, 0, len(object))
+	for field := range object {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		property, isExist := s.Properties[field]
+		if !isExist {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				return fmt.Errorf("%s: unexpected field %s", at, field)
+			}
+
+			continue
+		}
+
+		if err := property.validate(at+"."+field, object[field]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) validateType(at string, value any) error {
+	var types []string
+	switch t := s.Type.(type) {
+	case nil:
+		return nil
+	case string:
+		types = []string{t}
+	case []any:
+		for _, item := range t {
+			if name, ok := item.(string); ok {
+				types = append(types, name)
+			}
+		}
+	}
+
+	actual := typeOf(value)
+	for _, expected := range types {
+		if expected == actual || expected == "number" && actual == "integer" {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s: expected %s, got %s", at, strings.Join(types, " or "), actual)
+}
+
+func (s *Schema) inEnum(value any) bool {
+	for _, allowed := range s.Enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func typeOf(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		number, err := v.Float64()
+		if err == nil && number == math.Trunc(number) && !strings.ContainsAny(v.String(), ".eE") {
+			return "integer"
+		}
+
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,152 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEverySchemaIsLoaded(t *testing.T) {
+	for _, name := range []string{
+		"envelope.v1",
+		"order.created.v1",
+		"order.created.v2",
+		"order.cancelled.v1",
+		"order.expired.v1",
+		"stock.update.v1",
+		"stock.rollback.v1",
+		"stock.reservation.confirm.v1",
+		"stock.reservation.release.v1",
+		"payment.success.v1",
+		"payment.failed.v1",
+	} {
+		if !Has(name) {
+			t.Errorf("schema %s is not loaded", name)
+		}
+	}
+}
+
+func TestName(t *testing.T) {
+	if got := Name("order.created", 2); got != "order.created.v2" {
+		t.Errorf("Name() = %s, want order.created.v2", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		document string
+		// wantErr is part of the expected error, empty when the document is valid
+		wantErr string
+	}{
+		{
+			name:     "valid payment",
+			schema:   "payment.success.v1",
+			document: `{"order_id": 7, "status": "success"}`,
+		},
+		{
+			name:     "optional field left out",
+			schema:   "payment.success.v1",
+			document: `{"order_id": 7}`,
+		},
+		{
+			name:     "missing required field",
+			schema:   "payment.success.v1",
+			document: `{"status": "success"}`,
+			wantErr:  "missing required field order_id",
+		},
+		{
+			name:     "integer sent as string",
+			schema:   "payment.success.v1",
+			document: `{"order_id": "7"}`,
+			wantErr:  "$.order_id: expected integer, got string",
+		},
+		{
+			name:     "fraction where an integer is required",
+			schema:   "payment.success.v1",
+			document: `{"order_id": 7.5}`,
+			wantErr:  "$.order_id: expected integer, got number",
+		},
+		{
+			name:     "below minimum",
+			schema:   "payment.success.v1",
+			document: `{"order_id": 0}`,
+			wantErr:  "$.order_id: 0 is below 1",
+		},
+		{
+			name:     "payload that is not an object",
+			schema:   "payment.success.v1",
+			document: `[7]`,
+			wantErr:  "$: expected object, got array",
+		},
+		{
+			name:     "invalid JSON",
+			schema:   "payment.success.v1",
+			document: `{"order_id": `,
+			wantErr:  "schema payment.success.v1",
+		},
+		{
+			name:     "valid stock update",
+			schema:   "stock.update.v1",
+			document: `{"order_id": 7, "products": [{"product_id": 11, "quantity": 2}], "event_time": "2026-10-18T09:30:15.25Z"}`,
+		},
+		{
+			name:     "invalid array item",
+			schema:   "stock.update.v1",
+			document: `{"order_id": 7, "products": [{"product_id": 11}], "event_time": "2026-10-18T09:30:15Z"}`,
+			wantErr:  "$.products[0]: missing required field quantity",
+		},
+		{
+			name:     "invalid date-time",
+			schema:   "stock.update.v1",
+			document: `{"order_id": 7, "products": [], "event_time": "18/10/2026"}`,
+			wantErr:  `$.event_time: "18/10/2026" is not a date-time`,
+		},
+		{
+			name:     "empty string below min length",
+			schema:   "stock.reservation.release.v1",
+			document: `{"order_id": 7, "reservation_id": "", "event_time": "2026-10-18T09:30:15Z"}`,
+			wantErr:  "$.reservation_id: shorter than 1",
+		},
+		{
+			name:     "nullable array",
+			schema:   "order.created.v2",
+			document: `{"order_id": 7, "user_id": 42, "total_amount": 10.5, "currency": "USD", "base_amount": 170625, "base_currency": "IDR", "exchange_rate": "16250", "total_qty": 1, "payment_method": "card", "shipping_address": "Jl. Sudirman 1", "items": null}`,
+		},
+		{
+			name:     "v2 requires the currency fields v1 did not",
+			schema:   "order.created.v2",
+			document: `{"order_id": 7, "user_id": 42, "total_amount": 10.5, "total_qty": 1, "payment_method": "card", "shipping_address": "Jl. Sudirman 1"}`,
+			wantErr:  "missing required field currency",
+		},
+		{
+			name:     "v1 without the currency fields",
+			schema:   "order.created.v1",
+			document: `{"order_id": 7, "user_id": 42, "total_amount": 10.5, "total_qty": 1, "payment_method": "card", "shipping_address": "Jl. Sudirman 1"}`,
+		},
+		{
+			name:     "unknown schema",
+			schema:   "order.created.v9",
+			document: `{}`,
+			wantErr:  "unknown schema order.created.v9",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(tc.schema, []byte(tc.document))
+
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want nil", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
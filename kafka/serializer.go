@@ -0,0 +1,112 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"order/models"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	HeaderContentType = "content-type"
+
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+
+	SerializerJSON     = "json"
+	SerializerProtobuf = "protobuf"
+)
+
+// Serializer converts an event envelope to and from the bytes sent to kafka. The content type is
+// sent as a header so consumers pick the right serializer while topics migrate between formats.
+type Serializer interface {
+	ContentType() string
+	Marshal(envelope models.EventEnvelope) ([]byte, error)
+	Unmarshal(value []byte) (models.EventEnvelope, error)
+}
+
+type JSONSerializer struct{}
+
+func (JSONSerializer) ContentType() string {
+	return ContentTypeJSON
+}
+
+func (JSONSerializer) Marshal(envelope models.EventEnvelope) ([]byte, error) {
+	return json.Marshal(envelope)
+}
+
+// Unmarshal returns an envelope without a type for bare payloads, DecodeMessage falls back to the topic then.
+func (JSONSerializer) Unmarshal(value []byte) (models.EventEnvelope, error) {
+	var envelope models.EventEnvelope
+
+	err := json.Unmarshal(value, &envelope)
+	if err != nil {
+		return models.EventEnvelope{}, err
+	}
+
+	if envelope.Type == "" || len(envelope.Payload) == 0 {
+		return models.EventEnvelope{Payload: value}, nil
+	}
+
+	return envelope, nil
+}
+
+// SerializerForContentType picks the serializer of a consumed message, messages without the header
+// are JSON.
+func SerializerForContentType(contentType string) (Serializer, error) {
+	switch contentType {
+	case "", ContentTypeJSON:
+		return JSONSerializer{}, nil
+	case ContentTypeProtobuf:
+		return ProtobufSerializer{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported content type %q", contentType)
+	}
+}
+
+// ParseTopicSerializers reads a "topic:format" comma list such as "order.created:protobuf".
+// Topics that are not listed are published as JSON.
+func ParseTopicSerializers(value string) (map[string]Serializer, error) {
+	serializers := make(map[string]Serializer)
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		topic, format, isFound := strings.Cut(entry, ":")
+		if !isFound {
+			return nil, fmt.Errorf("invalid topic serializer %q", entry)
+		}
+
+		topic = strings.TrimSpace(topic)
+
+		switch strings.TrimSpace(format) {
+		case SerializerJSON:
+			serializers[topic] = JSONSerializer{}
+		case SerializerProtobuf:
+			if _, isExist := protoCodecs[topic]; !isExist {
+				return nil, fmt.Errorf("topic %s has no protobuf definition", topic)
+			}
+
+			serializers[topic] = ProtobufSerializer{}
+		default:
+			return nil, fmt.Errorf("unknown serializer %q for topic %s", format, topic)
+		}
+	}
+
+	return serializers, nil
+}
+
+func contentTypeHeader(headers []kafka.Header) string {
+	for _, header := range headers {
+		if header.Key == HeaderContentType {
+			return string(header.Value)
+		}
+	}
+
+	return ""
+}
@@ -2,19 +2,39 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"order/cmd/order/handler"
 	"order/cmd/order/repository"
 	"order/cmd/order/resource"
 	"order/cmd/order/service"
 	"order/cmd/order/usecase"
+	"order/cmd/order/worker"
 	"order/config"
+	"order/files/migrations"
+	"order/infrastructure/cart"
+	"order/infrastructure/constant"
+	"order/infrastructure/exchangerate"
+	"order/infrastructure/idempotency"
 	"order/infrastructure/log"
+	"order/infrastructure/migration"
+	"order/infrastructure/product"
+	"order/infrastructure/shipping"
+	"order/infrastructure/tax"
 	"order/kafka"
 	kafkaConsumer "order/kafka/consumer"
 	"order/routes"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -24,31 +44,304 @@ func main() {
 	// init connection
 	db := resource.InitDb(&cfg)
 
+	// setup logger
+	log.SetupLogger()
+
 	// kafka producer init
-	kafkaHost := fmt.Sprintf("%s:%s", cfg.Kafka.Host, cfg.App.Port)
-	kafkaProducer := kafka.NewKafkaProducer([]string{kafkaHost})
+	kafkaBrokers := []string{fmt.Sprintf("%s:%s", cfg.Kafka.Host, cfg.Kafka.Port)}
+	kafkaSerializers, err := kafka.ParseTopicSerializers(cfg.Kafka.TopicSerializers)
+	if err != nil {
+		log.Logger.Fatalf("invalid kafka topic serializers: %v", err)
+	}
+
+	kafkaProducer := kafka.NewKafkaProducer(kafkaBrokers, kafkaSerializers)
 	defer kafkaProducer.Close()
 
-	// setup logger
-	log.SetupLogger()
+	// admin commands, e.g. `order replay-dlq payment.failed.dlq` or `order migrate up`
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1:], db, kafkaBrokers, kafkaProducer)
+		return
+	}
+
+	if cfg.Database.MigrateOnStartup {
+		runMigrate(db, []string{"up"})
+	}
 
 	// user setup
-	orderRepository := repository.NewOrderRepository(db, cfg.Product.Host)
-	orderService := service.NewOrderService(orderRepository)
-	orderUsecase := usecase.NewOrderUsecase(orderService, kafkaProducer)
+	productClient := product.NewClient(cfg.Product, initProductCache(&cfg))
+	orderRepository := repository.NewOrderRepository(db)
+	orderService := service.NewOrderService(orderRepository, productClient)
+	orderUsecase := usecase.NewOrderUsecase(orderService, initIdempotencyStore(&cfg, db), cfg.Idempotency, initRateProvider(&cfg), initTaxCalculator(&cfg), initShippingProvider(&cfg), cfg.Shipping, initCartStore(&cfg, db))
 	orderHandler := handler.NewOrderHandler(orderUsecase)
 
+	// root context, cancelled on SIGINT / SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var workers sync.WaitGroup
+	runWorker := func(start func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			start(ctx)
+		}()
+	}
+
+	// outbox relay
+	outboxRelay := worker.NewOutboxRelay(orderService, kafkaProducer, cfg.Outbox)
+	runWorker(outboxRelay.Start)
+
+	// unpaid order expiry
+	orderExpiryScheduler := worker.NewOrderExpiryScheduler(orderUsecase, cfg.Expiry)
+	runWorker(orderExpiryScheduler.Start)
+
+	// kafka consumer
+	retryPolicy := kafkaConsumer.NewRetryPolicy(cfg.Kafka.ConsumerMaxAttempts, cfg.Kafka.ConsumerRetryBackoff, cfg.Kafka.ConsumerMaxRetryBackoff)
+	consumerConfig := func(topic string) kafkaConsumer.Config {
+		return kafkaConsumer.Config{
+			Brokers:     kafkaBrokers,
+			Topic:       topic,
+			Concurrency: cfg.Kafka.ConsumerConcurrency,
+			RetryPolicy: retryPolicy,
+		}
+	}
+
+	paymentSuccessConsumer := kafkaConsumer.NewPaymentSuccessConsumer(orderService, kafkaProducer)
+	paymentFailedConsumer := kafkaConsumer.NewPaymentFailedConsumer(orderService, kafkaProducer)
+
+	consumerRuntime := kafkaConsumer.NewRuntime(
+		kafkaConsumer.New(consumerConfig(constant.TopicPaymentSuccess), kafkaProducer, paymentSuccessConsumer.Handle()),
+		kafkaConsumer.New(consumerConfig(constant.TopicPaymentFailed), kafkaProducer, paymentFailedConsumer.Handle()),
+	)
+	runWorker(func(ctx context.Context) {
+		if err := consumerRuntime.Run(ctx); err != nil {
+			log.Logger.Errorf("consumer runtime stopped with error: %v", err)
+		}
+	})
+
+	// http server
 	port := cfg.App.Port
 	router := gin.Default()
 	routes.SetupRoutes(router, *orderHandler, cfg.Jwt.Secret)
-	router.Run(":" + port)
 
-	// kafka consumer
-	kafkaPaymentSuccessConsumer := kafkaConsumer.NewPaymentSuccessConsumer([]string{"localhost:9093"}, "payment.success", *orderService, *kafkaProducer)
-	kafkaPaymentSuccessConsumer.Start(context.Background())
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: router,
+	}
+
+	go func() {
+		log.Logger.Printf("Server listening on port: %s", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Logger.Errorf("server stopped with error: %v", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	log.Logger.Println("Shutting down...")
+
+	shutdownTimeout := cfg.App.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 15 * time.Second
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Logger.Errorf("server shutdown got error: %v", err)
+	}
+
+	// wait for consumers and workers to drain, the producer is closed afterwards by the deferred Close
+	drained := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		log.Logger.Println("Shutdown complete")
+	case <-shutdownCtx.Done():
+		log.Logger.Println("Shutdown timed out before every worker stopped")
+	}
+}
+
+func initIdempotencyStore(cfg *config.Config, db *gorm.DB) idempotency.Store {
+	if cfg.Idempotency.Store == idempotency.StorePostgres {
+		return idempotency.NewPostgresStore(db)
+	}
+
+	return idempotency.NewRedisStore(initRedis(cfg))
+}
+
+func initCartStore(cfg *config.Config, db *gorm.DB) cart.Store {
+	store := cart.NewPostgresStore(db)
+	if cfg.Cart.Store == cart.StorePostgres {
+		return store
+	}
+
+	return cart.NewRedisStore(initRedis(cfg), store, cfg.Cart.CacheTTL)
+}
+
+func initRateProvider(cfg *config.Config) exchangerate.Provider {
+	if cfg.Currency.RateProvider == exchangerate.ProviderFile {
+		provider, err := exchangerate.NewFileProvider(cfg.Currency.RatesFile)
+		if err != nil {
+			log.Logger.Fatalf("load exchange rates: %v", err)
+		}
+
+		return provider
+	}
+
+	rates, err := exchangerate.ParseRates(cfg.Currency.Rates)
+	if err != nil {
+		log.Logger.Fatalf("invalid exchange rates: %v", err)
+	}
+
+	provider, err := exchangerate.NewStaticProvider(cfg.Currency.BaseCurrency, rates)
+	if err != nil {
+		log.Logger.Fatalf("invalid exchange rates: %v", err)
+	}
+
+	return provider
+}
+
+func initTaxCalculator(cfg *config.Config) tax.Calculator {
+	if cfg.Tax.Calculator != tax.CalculatorTable {
+		return tax.NoTax{}
+	}
+
+	calculator, err := tax.NewFileCalculator(cfg.Tax.TableFile)
+	if err != nil {
+		log.Logger.Fatalf("load tax table: %v", err)
+	}
+
+	return calculator
+}
+
+func initShippingProvider(cfg *config.Config) shipping.Provider {
+	if cfg.Shipping.Provider != shipping.ProviderTable {
+		return shipping.FreeShipping{}
+	}
+
+	provider, err := shipping.NewFileProvider(cfg.Shipping.RatesFile)
+	if err != nil {
+		log.Logger.Fatalf("load shipping rates: %v", err)
+	}
+
+	return provider
+}
+
+func initProductCache(cfg *config.Config) product.Cache {
+	if cfg.Product.CacheStore == product.CacheStoreRedis {
+		return product.NewRedisCache(initRedis(cfg))
+	}
+
+	return product.NewMemoryCache()
+}
+
+// initRedis connects to redis on first use, so it is only required when a feature is configured to use it.
+func initRedis(cfg *config.Config) *redis.Client {
+	if resource.RedisClient != nil {
+		return resource.RedisClient
+	}
+
+	return resource.InitRedis(cfg)
+}
+
+func runCommand(args []string, db *gorm.DB, kafkaBrokers []string, kafkaProducer *kafka.KafkaProducer) {
+	switch args[0] {
+	case "migrate":
+		// migrate [up | down [steps] | status]
+		runMigrate(db, args[1:])
+	case "replay-dlq":
+		// replay-dlq <dlq topic> [limit]
+		if len(args) < 2 {
+			log.Logger.Fatal("usage: order replay-dlq <dlq topic> [limit]")
+		}
+
+		limit := 0
+		if len(args) > 2 {
+			var err error
+			limit, err = strconv.Atoi(args[2])
+			if err != nil || limit < 0 {
+				log.Logger.Fatalf("invalid limit %q", args[2])
+			}
+		}
+
+		replayed, err := kafkaConsumer.ReplayDLQ(context.Background(), kafkaBrokers, args[1], kafkaProducer, limit, 5*time.Second)
+		if err != nil {
+			log.Logger.Fatalf("replay %s stopped after %d messages: %v", args[1], replayed, err)
+		}
+
+		log.Logger.Printf("Replayed %d messages from %s", replayed, args[1])
+	default:
+		log.Logger.Fatalf("unknown command %q", args[0])
+	}
+}
+
+func runMigrate(db *gorm.DB, args []string) {
+	migrator, err := migration.NewMigrator(db, migrations.Files)
+	if err != nil {
+		log.Logger.Fatalf("load migrations: %v", err)
+	}
+
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	ctx := context.Background()
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Logger.Fatalf("migrate up: %v", err)
+		}
+
+		for _, m := range applied {
+			log.Logger.Printf("Applied migration %d_%s", m.Version, m.Name)
+		}
+
+		log.Logger.Printf("Schema is up to date, %d migrations applied", len(applied))
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				log.Logger.Fatalf("invalid steps %q", args[1])
+			}
+		}
+
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			log.Logger.Fatalf("migrate down: %v", err)
+		}
+
+		for _, m := range reverted {
+			log.Logger.Printf("Reverted migration %d_%s", m.Version, m.Name)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Logger.Fatalf("migrate status: %v", err)
+		}
+
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied at " + status.AppliedTime.Format(time.RFC3339)
+			}
 
-	kafkaPaymentFailedConsumer := kafkaConsumer.NewPaymentFailedConsumer([]string{"localhost:9093"}, "payment.failed", *orderService, *kafkaProducer)
-	kafkaPaymentFailedConsumer.Start(context.Background())
+			if status.Modified {
+				state += ", modified since"
+			}
 
-	log.Logger.Printf("Server listening on port: %s", port)
+			log.Logger.Printf("%d_%s: %s", status.Version, status.Name, state)
+		}
+	default:
+		log.Logger.Fatal("usage: order migrate [up | down [steps] | status]")
+	}
 }
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// OptionalAuthMiddleware lets guests through without a user_id. A token that is sent must still be valid.
+func OptionalAuthMiddleware(jwtSecret string) gin.HandlerFunc {
+	auth := AuthMiddleware(jwtSecret)
+
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+
+		auth(c)
+	}
+}
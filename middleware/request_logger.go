@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"order/infrastructure/constant"
 	"order/infrastructure/log"
 	"time"
 
@@ -17,7 +18,7 @@ func RequestLogger(timeout time.Duration) gin.HandlerFunc {
 		timeoutCtx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
 		defer cancel()
 
-		ctx := context.WithValue(timeoutCtx, "request_id", requestID)
+		ctx := context.WithValue(timeoutCtx, constant.ContextKeyRequestID, requestID)
 		c.Request = c.Request.WithContext(ctx)
 
 		startTime := time.Now()
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Address is a saved shipping address. Country is an ISO 3166-1 alpha-2 code and Region the subdivision
+// part of ISO 3166-2, such as JK for Jakarta.
+type Address struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id"`
+	Label      string    `json:"label"`
+	Recipient  string    `json:"recipient"`
+	Phone      string    `json:"phone"`
+	Line1      string    `json:"line1"`
+	Line2      string    `json:"line2"`
+	City       string    `json:"city"`
+	Region     string    `json:"region"`
+	PostalCode string    `json:"postal_code"`
+	Country    string    `json:"country"`
+	IsDefault  bool      `json:"is_default"`
+	CreateTime time.Time `json:"create_time"`
+	UpdateTime time.Time `json:"update_time"`
+}
+
+type AddressRequest struct {
+	Label      string `json:"label"`
+	Recipient  string `json:"recipient"`
+	Phone      string `json:"phone"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2"`
+	City       string `json:"city"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+	IsDefault  bool   `json:"is_default"`
+}
+
+type AddressParam struct {
+	UserID    int64
+	AddressID int64
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"order/infrastructure/money"
+	"time"
+)
+
+// Cart is a server-side shopping cart. A guest cart has no UserID and is reached by its ID alone.
+type Cart struct {
+	ID         string     `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Items      []CartItem `json:"items" gorm:"serializer:json"`
+	Version    int64      `json:"version"`
+	CreateTime time.Time  `json:"create_time"`
+	UpdateTime time.Time  `json:"update_time"`
+}
+
+// CartItem keeps the name and base currency price the product had when the cart was last priced.
+type CartItem struct {
+	ProductID   int64        `json:"product_id"`
+	ProductName string       `json:"product_name"`
+	Quantity    int          `json:"quantity"`
+	UnitPrice   money.Amount `json:"unit_price"`
+}
+
+type CartParam struct {
+	UserID   int64
+	CartID   string
+	Currency string
+}
+
+type CartItemRequest struct {
+	ProductID int64 `json:"product_id"`
+	Quantity  int   `json:"quantity"`
+}
+
+type MergeCartRequest struct {
+	GuestCartID string `json:"guest_cart_id"`
+}
+
+type CartResponse struct {
+	CartID   string             `json:"cart_id"`
+	Currency string             `json:"currency"`
+	Items    []CartItemResponse `json:"items"`
+	TotalQty int                `json:"total_qty"`
+	Subtotal money.Amount       `json:"subtotal"`
+}
+
+// CartItemResponse is a cart line priced against product service. PriceChanged tells the client the
+// price moved since the item was last priced, Available that the stock still covers the quantity.
+type CartItemResponse struct {
+	ProductID    int64        `json:"product_id"`
+	ProductName  string       `json:"product_name"`
+	Quantity     int          `json:"quantity"`
+	UnitPrice    money.Amount `json:"unit_price"`
+	LineTotal    money.Amount `json:"line_total"`
+	PriceChanged bool         `json:"price_changed"`
+	Available    bool         `json:"available"`
+}
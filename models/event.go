@@ -0,0 +1,24 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ProcessedEvent is a ledger entry recording that a consumer already handled an event.
+type ProcessedEvent struct {
+	Consumer      string    `json:"consumer"`
+	EventKey      string    `json:"event_key"`
+	ProcessedTime time.Time `json:"processed_time"`
+}
+
+// EventEnvelope wraps every event published to kafka.
+type EventEnvelope struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	Version       int             `json:"version"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Producer      string          `json:"producer"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+}
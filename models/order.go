@@ -1,16 +1,38 @@
 package models
 
-import "time"
+import (
+	"order/infrastructure/money"
+	"time"
+)
 
 type Order struct {
-	ID              int64   `json:"id"`
-	UserID          int64   `json:"user_id"`
-	OrderDetailID   int64   `json:"order_detail_id"`
-	Amount          float64 `json:"amount"`
-	TotalQty        int     `json:"total_qtr"`
-	Status          int     `json:"status"`
-	PaymentMethod   string  `json:"payment_method"`
-	ShippingAddress string  `json:"shipping_address"`
+	ID                         int64        `json:"id"`
+	UserID                     int64        `json:"user_id"`
+	OrderDetailID              int64        `json:"order_detail_id"`
+	Subtotal                   money.Amount `json:"subtotal"`
+	DiscountAmount             money.Amount `json:"discount_amount"`
+	TaxAmount                  money.Amount `json:"tax_amount"`
+	TaxMode                    string       `json:"tax_mode"`
+	Amount                     money.Amount `json:"amount"`
+	Currency                   string       `json:"currency"`
+	BaseAmount                 money.Amount `json:"base_amount"`
+	BaseCurrency               string       `json:"base_currency"`
+	ExchangeRate               money.Rate   `json:"exchange_rate"`
+	ExchangeRateTime           time.Time    `json:"exchange_rate_time"`
+	TotalQty                   int          `json:"total_qtr"`
+	Status                     int          `json:"status"`
+	PaymentMethod              string       `json:"payment_method"`
+	ShippingAddress            string       `json:"shipping_address"`
+	ShippingAddressDetail      *Address     `json:"shipping_address_detail" gorm:"serializer:json"`
+	ShippingCountry            string       `json:"shipping_country"`
+	ShippingRegion             string       `json:"shipping_region"`
+	ShippingMethod             string       `json:"shipping_method"`
+	ShippingCarrier            string       `json:"shipping_carrier"`
+	ShippingFee                money.Amount `json:"shipping_fee"`
+	StockReservationID         string       `json:"stock_reservation_id"`
+	StockReservationExpireTime *time.Time   `json:"stock_reservation_expire_time"`
+	CreateTime                 time.Time    `json:"create_time"`
+	UpdateTime                 time.Time    `json:"update_time"`
 }
 
 type OrderDetail struct {
@@ -20,62 +42,203 @@ type OrderDetail struct {
 }
 
 type CheckoutItem struct {
-	ProductID int64   `json:"product_id"`
-	Quantity  int     `json:"quantity"`
-	Price     float64 `json:"price"`
+	ProductID int64        `json:"product_id"`
+	Quantity  int          `json:"quantity"`
+	Price     money.Amount `json:"price"`
 }
 
 type CheckoutRequest struct {
 	UserID            int64          `json:"user_id"`
 	Items             []CheckoutItem `json:"items"`
+	CartID            string         `json:"cart_id"`
 	PaymentMethod     string         `json:"payment_method"`
 	ShippingAddress   string         `json:"shipping_address"`
+	ShippingAddressID int64          `json:"shipping_address_id"`
+	ShippingCountry   string         `json:"shipping_country"`
+	ShippingRegion    string         `json:"shipping_region"`
+	ShippingMethod    string         `json:"shipping_method"`
+	Currency          string         `json:"currency"`
+	CouponCodes       []string       `json:"coupon_codes"`
 	IdempontencyToken string         `json:"idempontency_token"`
 }
 
 type OrderHistoryParam struct {
-	UserID int64
-	Status int
+	UserID        int64
+	Statuses      []int
+	StartTime     *time.Time
+	EndTime       *time.Time
+	Cursor        string
+	After         *OrderHistoryCursor
+	Limit         int
+	SortDirection string
+}
+
+// OrderHistoryCursor is the keyset position of the last order returned in a page.
+type OrderHistoryCursor struct {
+	CreateTime time.Time `json:"create_time"`
+	ID         int64     `json:"id"`
+}
+
+type OrderHistoryPage struct {
+	Orders     []OrderHistoryResponse `json:"orders"`
+	NextCursor string                 `json:"next_cursor"`
+	HasMore    bool                   `json:"has_more"`
+}
+
+type OrderInfoParam struct {
+	UserID  int64
+	OrderID int64
+}
+
+type CancelOrderRequest struct {
+	Reason string `json:"reason"`
+}
+
+type CancelOrderParam struct {
+	UserID  int64
+	OrderID int64
+	Reason  string
 }
 
 type StatusHistory struct {
-	Status    string `json:"status"`
-	Timestamp string `json:"timestamp"`
+	Status        string `json:"status"`
+	FromStatus    string `json:"from_status,omitempty"`
+	Timestamp     string `json:"timestamp"`
+	Reason        string `json:"reason,omitempty"`
+	ActorType     string `json:"actor_type,omitempty"`
+	ActorID       string `json:"actor_id,omitempty"`
+	SourceEventID string `json:"source_event_id,omitempty"`
+}
+
+// OrderStatusHistory is one row of order_status_history. FromStatus is nil for the row written at checkout.
+type OrderStatusHistory struct {
+	ID            int64     `json:"id"`
+	OrderID       int64     `json:"order_id"`
+	FromStatus    *int      `json:"from_status"`
+	ToStatus      int       `json:"to_status"`
+	ActorType     string    `json:"actor_type"`
+	ActorID       string    `json:"actor_id"`
+	Reason        string    `json:"reason"`
+	SourceEventID string    `json:"source_event_id"`
+	CreateTime    time.Time `json:"create_time"`
+}
+
+type UpdateOrderStatusParam struct {
+	OrderID       int64
+	Status        int
+	Reason        string
+	ActorType     string
+	ActorID       string
+	SourceEventID string
 }
 
 type OrderHistoryResponse struct {
-	OrderID         int64           `json:"order_id"`
-	TotalAmount     float64         `json:"total_amount"`
-	TotalQty        int             `json:"total_qty"`
-	Status          string          `json:"status"`
-	PaymentMethod   string          `json:"payment_method"`
-	ShippingAddress string          `json:"shipping_address"`
-	Products        []CheckoutItem  `json:"products"`
-	History         []StatusHistory `json:"history"`
+	OrderID               int64             `json:"order_id"`
+	Subtotal              money.Amount      `json:"subtotal"`
+	DiscountAmount        money.Amount      `json:"discount_amount"`
+	Discounts             []AppliedDiscount `json:"discounts"`
+	TaxAmount             money.Amount      `json:"tax_amount"`
+	TaxMode               string            `json:"tax_mode"`
+	ShippingMethod        string            `json:"shipping_method"`
+	ShippingFee           money.Amount      `json:"shipping_fee"`
+	TotalAmount           money.Amount      `json:"total_amount"`
+	Currency              string            `json:"currency"`
+	TotalQty              int               `json:"total_qty"`
+	Status                string            `json:"status"`
+	PaymentMethod         string            `json:"payment_method"`
+	ShippingAddress       string            `json:"shipping_address"`
+	ShippingAddressDetail *Address          `json:"shipping_address_detail,omitempty"`
+	Products              []CheckoutItem    `json:"products"`
+	History               []StatusHistory   `json:"history"`
+	CreateTime            time.Time         `json:"create_time"`
 }
 
 type OrderRequestLog struct {
-	ID                int64     `json:"id"`
-	IdempontencyToken string    `json:"idempontency_token"`
-	CreateTime        time.Time `json:"create_time"`
+	ID             int64     `json:"id"`
+	IdempotencyKey string    `json:"idempotency_key"`
+	Status         string    `json:"status"`
+	OrderID        int64     `json:"order_id"`
+	OwnerToken     *string   `json:"owner_token"`
+	CreateTime     time.Time `json:"create_time"`
+	ExpireTime     time.Time `json:"expire_time"`
+}
+
+type CheckoutResponse struct {
+	OrderID  int64
+	Replayed bool
 }
 
 type OrderHistoryResult struct {
-	ID              int64 `gorm:"column:id"`
-	Amount          float64
-	TotalQty        int
-	Status          int
-	PaymentMethod   string
-	ShippingAddress string
-	Products        string `gorm:"column:products"`
-	OrderHistory    string `gorm:"column:order_history"`
+	ID                    int64 `gorm:"column:id"`
+	Subtotal              money.Amount
+	DiscountAmount        money.Amount
+	TaxAmount             money.Amount
+	TaxMode               string
+	ShippingMethod        string
+	ShippingFee           money.Amount
+	Amount                money.Amount
+	Currency              string
+	TotalQty              int
+	Status                int
+	PaymentMethod         string
+	ShippingAddress       string
+	ShippingAddressDetail *Address  `gorm:"serializer:json"`
+	Products              string    `gorm:"column:products"`
+	OrderHistory          string    `gorm:"column:order_history"`
+	CreateTime            time.Time `gorm:"column:create_time"`
 }
 
 type OrderCreatedEvent struct {
-	OrderID         int64   `json:"order_id"`
-	UserID          int64   `json:"user_id"`
-	TotalAmount     float64 `json:"total_amount"`
-	TotalQty        int     `json:"total_qty"`
-	PaymentMethod   string  `json:"payment_method"`
-	ShippingAddress string  `json:"shipping_address"`
+	OrderID               int64        `json:"order_id"`
+	UserID                int64        `json:"user_id"`
+	Subtotal              money.Amount `json:"subtotal"`
+	DiscountAmount        money.Amount `json:"discount_amount"`
+	TaxAmount             money.Amount `json:"tax_amount"`
+	TaxMode               string       `json:"tax_mode"`
+	ShippingMethod        string       `json:"shipping_method"`
+	ShippingCarrier       string       `json:"shipping_carrier"`
+	ShippingFee           money.Amount `json:"shipping_fee"`
+	TotalAmount           money.Amount `json:"total_amount"`
+	Currency              string       `json:"currency"`
+	BaseAmount            money.Amount `json:"base_amount"`
+	BaseCurrency          string       `json:"base_currency"`
+	ExchangeRate          money.Rate   `json:"exchange_rate"`
+	TotalQty              int          `json:"total_qty"`
+	PaymentMethod         string       `json:"payment_method"`
+	ShippingAddress       string       `json:"shipping_address"`
+	ShippingAddressDetail *Address     `json:"shipping_address_detail,omitempty"`
+	Items                 []OrderLine  `json:"items"`
+}
+
+// OrderLine is an order line as the payment service needs it to charge and itemise tax.
+type OrderLine struct {
+	ProductID      int64        `json:"product_id"`
+	Quantity       int          `json:"quantity"`
+	UnitPrice      money.Amount `json:"unit_price"`
+	LineTotal      money.Amount `json:"line_total"`
+	DiscountAmount money.Amount `json:"discount_amount"`
+	TaxRateBps     int64        `json:"tax_rate_bps"`
+	TaxAmount      money.Amount `json:"tax_amount"`
+}
+
+type OrderCancelledEvent struct {
+	OrderID     int64        `json:"order_id"`
+	UserID      int64        `json:"user_id"`
+	TotalAmount money.Amount `json:"total_amount"`
+	Currency    string       `json:"currency"`
+	Reason      string       `json:"reason"`
+	CancelTime  time.Time    `json:"cancel_time"`
+}
+
+type OrderExpiredEvent struct {
+	OrderID     int64        `json:"order_id"`
+	UserID      int64        `json:"user_id"`
+	TotalAmount money.Amount `json:"total_amount"`
+	Currency    string       `json:"currency"`
+	ExpireTime  time.Time    `json:"expire_time"`
+}
+
+type ExpireOrdersParam struct {
+	CreatedBefore time.Time
+	Limit         int
 }
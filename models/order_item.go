@@ -0,0 +1,23 @@
+package models
+
+import (
+	"order/infrastructure/money"
+	"time"
+)
+
+// OrderItem is one product line of an order, with the product name and price as they were at checkout.
+// DiscountAmount is the line's share of the order discount and TaxRateBps is in basis points, 1100 is 11%.
+type OrderItem struct {
+	ID             int64        `json:"id"`
+	OrderID        int64        `json:"order_id"`
+	ProductID      int64        `json:"product_id"`
+	ProductName    string       `json:"product_name"`
+	CategoryID     int          `json:"category_id"`
+	UnitPrice      money.Amount `json:"unit_price"`
+	Quantity       int          `json:"quantity"`
+	LineTotal      money.Amount `json:"line_total"`
+	DiscountAmount money.Amount `json:"discount_amount"`
+	TaxRateBps     int64        `json:"tax_rate_bps"`
+	TaxAmount      money.Amount `json:"tax_amount"`
+	CreateTime     time.Time    `json:"create_time"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type OutboxMessage struct {
+	ID              int64      `json:"id"`
+	Topic           string     `json:"topic"`
+	MessageKey      string     `json:"message_key"`
+	Payload         string     `json:"payload"`
+	Attempts        int        `json:"attempts"`
+	LastError       string     `json:"last_error"`
+	NextAttemptTime time.Time  `json:"next_attempt_time"`
+	PublishedTime   *time.Time `json:"published_time"`
+	FailedTime      *time.Time `json:"failed_time"`
+	CreateTime      time.Time  `json:"create_time"`
+}
+
+type OutboxDrainParam struct {
+	BatchSize       int
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	// MaxAttempts is how many times a message is tried before it is parked
+	MaxAttempts int
+	// ClaimTTL is how long a batch being published is hidden from other replicas, it must outlive the write
+	ClaimTTL time.Duration
+}
@@ -1,18 +1,27 @@
 package models
 
-import "time"
+import (
+	"order/infrastructure/money"
+	"time"
+)
 
 type GetProductInfo struct {
 	Product `json:"product"`
 }
 
+type GetProductsInfo struct {
+	Products []Product `json:"products"`
+}
+
 type Product struct {
-	ID          int64   `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	Stock       int     `json:"stock"`
-	CategoryID  int     `json:"category_id"`
+	ID          int64        `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Price       money.Amount `json:"price"`
+	Stock       int          `json:"stock"`
+	CategoryID  int          `json:"category_id"`
+	// Weight is in grams, zero when product service does not know it
+	Weight int64 `json:"weight"`
 }
 
 type ProductStockUpdateEvent struct {
@@ -25,3 +34,27 @@ type ProductItem struct {
 	ProductID int64 `json:"product_id"`
 	Qty       int   `json:"quantity"`
 }
+
+type StockReservationRequest struct {
+	ReferenceID string        `json:"reference_id"`
+	Items       []ProductItem `json:"items"`
+	TTLSeconds  int64         `json:"ttl_seconds"`
+}
+
+type GetStockReservation struct {
+	StockReservation `json:"reservation"`
+}
+
+// StockReservation is stock product service holds for an order until ExpireTime, it is confirmed once
+// the order is paid or released when it will not be.
+type StockReservation struct {
+	ID         string    `json:"id"`
+	ExpireTime time.Time `json:"expire_time"`
+}
+
+type StockReservationEvent struct {
+	OrderID       int64     `json:"order_id"`
+	ReservationID string    `json:"reservation_id"`
+	Reason        string    `json:"reason"`
+	EventTime     time.Time `json:"event_time"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"order/infrastructure/money"
+	"time"
+)
+
+// Coupon is a promotion redeemable with its code, codes are stored upper case. Amounts are in the
+// base currency. MaxUses and MaxUsesPerUser of 0 mean unlimited.
+type Coupon struct {
+	ID             int64        `json:"id"`
+	Code           string       `json:"code"`
+	Type           string       `json:"type"`
+	DiscountValue  money.Amount `json:"discount_value"`
+	BuyProductID   int64        `json:"buy_product_id"`
+	BuyQty         int          `json:"buy_qty"`
+	GetQty         int          `json:"get_qty"`
+	MinSpend       money.Amount `json:"min_spend"`
+	MaxUses        int          `json:"max_uses"`
+	MaxUsesPerUser int          `json:"max_uses_per_user"`
+	UsedCount      int          `json:"used_count"`
+	StartTime      *time.Time   `json:"start_time"`
+	ExpireTime     *time.Time   `json:"expire_time"`
+	CreateTime     time.Time    `json:"create_time"`
+}
+
+// CouponRedemption is a coupon used by an order, DiscountAmount is in the order currency. ReleasedTime
+// is set once the order gave the coupon back, a released redemption no longer counts against limits.
+type CouponRedemption struct {
+	ID             int64        `json:"id"`
+	CouponID       int64        `json:"coupon_id"`
+	Code           string       `json:"code"`
+	UserID         int64        `json:"user_id"`
+	OrderID        int64        `json:"order_id"`
+	DiscountAmount money.Amount `json:"discount_amount"`
+	CreateTime     time.Time    `json:"create_time"`
+	ReleasedTime   *time.Time   `json:"released_time"`
+}
+
+type AppliedDiscount struct {
+	Code   string       `json:"code"`
+	Amount money.Amount `json:"amount"`
+}
+
+// DiscountBreakdown is the price of an order before and after its coupons.
+type DiscountBreakdown struct {
+	Subtotal       money.Amount
+	DiscountAmount money.Amount
+	Total          money.Amount
+	Redemptions    []CouponRedemption
+	Discounts      []AppliedDiscount
+}
@@ -0,0 +1,25 @@
+package models
+
+import "order/infrastructure/money"
+
+type ShippingQuoteRequest struct {
+	Items           []ProductItem `json:"items"`
+	ShippingCountry string        `json:"shipping_country"`
+	ShippingRegion  string        `json:"shipping_region"`
+	Currency        string        `json:"currency"`
+}
+
+// ShippingOption is a way to deliver the order, Fee is in the currency of the quote.
+type ShippingOption struct {
+	Method  string       `json:"method"`
+	Carrier string       `json:"carrier"`
+	Fee     money.Amount `json:"fee"`
+	MinDays int          `json:"min_days"`
+	MaxDays int          `json:"max_days"`
+}
+
+type ShippingQuoteResponse struct {
+	Currency string           `json:"currency"`
+	Weight   int64            `json:"weight"`
+	Options  []ShippingOption `json:"options"`
+}
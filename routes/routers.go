@@ -15,5 +15,22 @@ func SetupRoutes(router *gin.Engine, orderHandler handler.OrderHandler, jwtSecre
 	private := router.Group("/v1/order")
 	private.Use(authMiddleware)
 	private.POST("/checkout", orderHandler.CheckoutOrder)
+	private.POST("/shipping-quote", orderHandler.QuoteShipping)
 	private.GET("/history", orderHandler.GetOrderHistory)
+	private.GET("/addresses", orderHandler.GetAddresses)
+	private.POST("/addresses", orderHandler.CreateAddress)
+	private.PUT("/addresses/:id", orderHandler.UpdateAddress)
+	private.DELETE("/addresses/:id", orderHandler.DeleteAddress)
+	private.GET("/:id", orderHandler.GetOrderByID)
+	private.POST("/:id/cancel", orderHandler.CancelOrder)
+	private.POST("/cart/merge", orderHandler.MergeCart)
+
+	// guests keep a cart too, signing in is only needed to merge it and check out
+	cart := router.Group("/v1/order/cart")
+	cart.Use(middleware.OptionalAuthMiddleware(jwtSecret))
+	cart.POST("", orderHandler.CreateCart)
+	cart.GET("/:cart_id", orderHandler.GetCart)
+	cart.POST("/:cart_id/items", orderHandler.AddCartItem)
+	cart.PUT("/:cart_id/items/:product_id", orderHandler.UpdateCartItem)
+	cart.DELETE("/:cart_id/items/:product_id", orderHandler.RemoveCartItem)
 }